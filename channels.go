@@ -0,0 +1,265 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a channel health result is trusted before it is checked again
+const channelCheckTTL = 5 * time.Minute
+
+// ChannelStatus is one entry of PicoConfig.Channels as shown in the overview
+type ChannelStatus struct {
+	Name      string    `json:"name"`
+	Label     string    `json:"label"`
+	Enabled   bool      `json:"enabled"`
+	Healthy   bool      `json:"healthy"`
+	Detail    string    `json:"detail"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+var channelLabels = map[string]string{
+	"telegram": "Telegram",
+	"discord":  "Discord",
+	"slack":    "Slack",
+	"whatsapp": "WhatsApp",
+	"matrix":   "Matrix",
+	"email":    "Email",
+}
+
+type channelCheck struct {
+	healthy   bool
+	detail    string
+	checkedAt time.Time
+}
+
+var channelHealth = struct {
+	sync.Mutex
+	results    map[string]channelCheck
+	refreshing bool
+}{results: make(map[string]channelCheck)}
+
+// ── Channels ─────────────────────────────────────────────────────────────────
+
+func handleChannels(w http.ResponseWriter, r *http.Request) {
+	refresh := r.URL.Query().Get("refresh") == "1"
+	jsonResponse(w, map[string]interface{}{
+		"ok":       true,
+		"channels": listChannels(readConfig(currentRuntime()), refresh),
+	})
+}
+
+func handleToggleChannel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	name := r.FormValue("name")
+	enabled := r.FormValue("enabled") == "true"
+
+	rt := currentRuntime()
+	cfg := readConfig(rt)
+	ch, ok := cfg.Channels[name]
+	if !ok {
+		errorResponse(w, "No channel named "+name)
+		return
+	}
+	// "discord": null in the config decodes to a nil map
+	if ch == nil {
+		ch = map[string]interface{}{}
+		cfg.Channels[name] = ch
+	}
+	ch["enabled"] = enabled
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
+
+	state := "disabled"
+	if enabled {
+		state = "enabled"
+	}
+	okResponse(w, channelLabel(name)+" "+state+" — restart the agent to apply", nil)
+}
+
+func handleRemoveChannel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	name := r.FormValue("name")
+
+	rt := currentRuntime()
+	cfg := readConfig(rt)
+	if _, ok := cfg.Channels[name]; !ok {
+		errorResponse(w, "No channel named "+name)
+		return
+	}
+	delete(cfg.Channels, name)
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
+
+	channelHealth.Lock()
+	delete(channelHealth.results, name)
+	channelHealth.Unlock()
+	okResponse(w, channelLabel(name)+" removed", nil)
+}
+
+// ── Channel Helpers ──────────────────────────────────────────────────────────
+
+// listChannels reports every configured channel, running credential checks in
+// parallel for any whose cached result is missing, stale or refresh is set.
+func listChannels(cfg PicoConfig, refresh bool) []ChannelStatus {
+	statuses := make([]ChannelStatus, 0, len(cfg.Channels))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for name, ch := range cfg.Channels {
+		enabled, _ := ch["enabled"].(bool)
+		st := ChannelStatus{Name: name, Label: channelLabel(name), Enabled: enabled}
+
+		channelHealth.Lock()
+		cached, ok := channelHealth.results[name]
+		channelHealth.Unlock()
+		if ok && !refresh && time.Since(cached.checkedAt) < channelCheckTTL {
+			st.Healthy, st.Detail, st.CheckedAt = cached.healthy, cached.detail, cached.checkedAt
+			statuses = append(statuses, st)
+			continue
+		}
+
+		wg.Add(1)
+		go func(st ChannelStatus, ch map[string]interface{}) {
+			defer wg.Done()
+			healthy, detail := checkChannel(st.Name, ch)
+			result := channelCheck{healthy: healthy, detail: detail, checkedAt: time.Now()}
+			channelHealth.Lock()
+			channelHealth.results[st.Name] = result
+			channelHealth.Unlock()
+
+			st.Healthy, st.Detail, st.CheckedAt = healthy, detail, result.checkedAt
+			mu.Lock()
+			statuses = append(statuses, st)
+			mu.Unlock()
+		}(st, ch)
+	}
+	wg.Wait()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// cachedChannels is listChannels without the wait, for the system check:
+// channels come back with whatever result is cached, and any that are
+// missing or stale are checked in the background for the next poll.
+func cachedChannels(cfg PicoConfig) []ChannelStatus {
+	statuses := make([]ChannelStatus, 0, len(cfg.Channels))
+	stale := false
+	channelHealth.Lock()
+	for name, ch := range cfg.Channels {
+		enabled, _ := ch["enabled"].(bool)
+		st := ChannelStatus{Name: name, Label: channelLabel(name), Enabled: enabled, Detail: "Checking…"}
+		if cached, ok := channelHealth.results[name]; ok {
+			st.Healthy, st.Detail, st.CheckedAt = cached.healthy, cached.detail, cached.checkedAt
+			stale = stale || time.Since(cached.checkedAt) >= channelCheckTTL
+		} else {
+			stale = true
+		}
+		statuses = append(statuses, st)
+	}
+	refresh := stale && !channelHealth.refreshing
+	if refresh {
+		channelHealth.refreshing = true
+	}
+	channelHealth.Unlock()
+
+	if refresh {
+		go func() {
+			listChannels(cfg, false)
+			channelHealth.Lock()
+			channelHealth.refreshing = false
+			channelHealth.Unlock()
+		}()
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// checkChannel verifies a channel's credentials against its service
+func checkChannel(name string, ch map[string]interface{}) (bool, string) {
+	str := func(key string) string {
+		v, _ := ch[key].(string)
+		return v
+	}
+
+	switch name {
+	case "telegram":
+		if str("token") == "" {
+			return false, "No bot token"
+		}
+		ok, msg, _ := validateTelegramToken(str("token"))
+		return ok, msg
+	case "discord":
+		if str("token") == "" {
+			return false, "No bot token"
+		}
+		bot, err := getDiscordBot(str("token"))
+		if err != nil {
+			return false, err.Error()
+		}
+		return true, "Bot " + bot.Username + " connected"
+	case "slack":
+		if str("bot_token") == "" {
+			return false, "No bot token"
+		}
+		auth, err := slackAuthTest(str("bot_token"))
+		if err != nil {
+			return false, "Bot token: " + err.Error()
+		}
+		return true, "Bot @" + auth.User + " in " + auth.Team
+	case "whatsapp":
+		bridge := str("bridge_url")
+		if bridge == "" {
+			bridge = defaultWhatsAppBridge
+		}
+		if !bridgeReachable(bridge) {
+			return false, "Bridge not reachable at " + bridge
+		}
+		return true, "Bridge listening at " + bridge
+	case "matrix":
+		if str("access_token") == "" {
+			return false, "No access token"
+		}
+		userID, err := matrixWhoami(str("homeserver"), str("access_token"))
+		if err != nil {
+			return false, err.Error()
+		}
+		return true, "Signed in as " + userID
+	case "email":
+		es := emailSettingsFromConfig()
+		if es.IMAPHost == "" {
+			return false, "No IMAP server"
+		}
+		if _, err := testIMAP(es); err != nil {
+			return false, "IMAP: " + err.Error()
+		}
+		return true, "IMAP login OK for " + es.Address
+	}
+	return false, "No health check for this channel"
+}
+
+func channelLabel(name string) string {
+	if label, ok := channelLabels[name]; ok {
+		return label
+	}
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// imageRefPattern is the distribution reference grammar: an optional
+// registry host[:port], lowercase path components, then a tag and/or digest
+var imageRefPattern = regexp.MustCompile(`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?/)?` +
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+	`(?::[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+
+// ── Deployment ───────────────────────────────────────────────────────────────
+
+// handleDeployment reports the deployment mode and which engines are usable
+// on GET. POST switches mode as a background job, since pulling an image
+// onto a Pi takes minutes.
+func handleDeployment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		settings := readSettings()
+		resp := map[string]interface{}{
+			"mode":          "native",
+			"default_image": currentRuntime().ContainerImage(),
+			"engines":       containerEngines(),
+		}
+		if c := settings.Container; c != nil {
+			resp["mode"] = "container"
+			resp["container"] = c
+		}
+		okResponse(w, "", resp)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+
+	var next *ContainerSettings
+	if r.FormValue("mode") == "container" {
+		next = &ContainerSettings{
+			Engine: r.FormValue("engine"),
+			Method: r.FormValue("method"),
+			Image:  strings.TrimSpace(r.FormValue("image")),
+		}
+		if next.Image == "" {
+			next.Image = currentRuntime().ContainerImage()
+		}
+		if err := next.validate(); err != nil {
+			errorResponse(w, err.Error())
+			return
+		}
+	}
+
+	job, err := jobs.start("deploy", func(ctx context.Context, j *Job) (map[string]interface{}, error) {
+		return deploy(ctx, next, j)
+	})
+	if err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+	okResponse(w, "Deployment started", map[string]interface{}{"job_id": job.state.ID})
+}
+
+// deploy removes whatever service runs the agent now and brings it up the
+// new way; next is nil for a native service. The image is pulled before the
+// old service goes, so a failed pull leaves the agent running. The old and
+// new services share a name, container and env file, so they can't run side
+// by side — if the new one fails to start, the old one is put back instead.
+func deploy(ctx context.Context, next *ContainerSettings, j *Job) (map[string]interface{}, error) {
+	if next != nil {
+		j.SetStage("Pulling " + next.Image)
+		if err := runLogged(ctx, j, next.Engine, "pull", next.Image); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("Could not pull %s: %v", next.Image, err)
+		}
+	}
+
+	old := detectServiceManager()
+	home, _ := os.UserHomeDir()
+	hadService := false
+	if path, _ := old.Definition(ServiceSpec{Home: home}); fileExists(path) {
+		j.SetStage("Removing " + old.Name() + " service")
+		if err := old.Uninstall(); err != nil {
+			return nil, fmt.Errorf("Could not remove the %s service: %v", old.Name(), err)
+		}
+		hadService = true
+	}
+
+	settings := readSettings()
+	previous := settings.Container
+	settings.Container = next
+	if err := writeSettings(settings); err != nil {
+		return nil, fmt.Errorf("Failed to save settings: %v", err)
+	}
+
+	mgr := detectServiceManager()
+	if _, err := defaultServiceSpec(); err != nil {
+		return map[string]interface{}{"message": "Switched to a native service — install " + currentRuntime().Name() + " at System Check, then install the service"}, nil
+	}
+	j.SetStage("Starting " + mgr.Name())
+	ok, msg := installService(mgr)
+	if !ok {
+		return nil, restoreDeployment(j, mgr, old, previous, hadService, msg)
+	}
+	if next != nil {
+		j.Logf("%s", containerManager{*next}.containerState())
+	}
+	return map[string]interface{}{"message": msg}, nil
+}
+
+// ── Deployment Helpers ───────────────────────────────────────────────────────
+
+// restoreDeployment undoes a switch whose new service would not start:
+// it removes what was installed, puts the previous mode back in settings
+// and reinstalls the old service if there was one. The error says what
+// failed and whether the agent is running again.
+func restoreDeployment(j *Job, failed, old ServiceManager, previous *ContainerSettings, hadService bool, reason string) error {
+	j.SetStage("Restoring " + old.Name() + " service")
+	j.Logf("%s failed to start: %s", failed.Name(), reason)
+	if err := failed.Uninstall(); err != nil {
+		j.Logf("Could not remove the %s service: %v", failed.Name(), err)
+	}
+
+	settings := readSettings()
+	settings.Container = previous
+	if err := writeSettings(settings); err != nil {
+		return fmt.Errorf("%s — and the previous deployment could not be restored: Failed to save settings: %v", reason, err)
+	}
+	if !hadService {
+		return fmt.Errorf("%s — kept the previous deployment mode", reason)
+	}
+	if ok, msg := installService(old); !ok {
+		return fmt.Errorf("%s — and the %s service could not be restored: %s", reason, old.Name(), msg)
+	}
+	return fmt.Errorf("%s — the %s service was restored", reason, old.Name())
+}
+
+func (c ContainerSettings) validate() error {
+	engines := containerEngines()
+	info, ok := engines[c.Engine]
+	if !ok {
+		return fmt.Errorf("Unknown container engine: %s", c.Engine)
+	}
+	if !info["installed"] {
+		return fmt.Errorf("%s is not installed", c.Engine)
+	}
+	switch {
+	case c.Method != "run" && c.Method != "compose" && c.Method != "quadlet":
+		return fmt.Errorf("Unknown deployment method: %s", c.Method)
+	case c.Method == "compose" && !info["compose"]:
+		return fmt.Errorf("%s compose is not available — install the compose plugin or use %s run", c.Engine, c.Engine)
+	case c.Method == "quadlet" && !info["quadlet"]:
+		return fmt.Errorf("Quadlet needs podman 4.4 or newer on a systemd machine")
+	}
+	// A leading - would be read as an option by the engine
+	if strings.HasPrefix(c.Image, "-") || !imageRefPattern.MatchString(c.Image) {
+		return fmt.Errorf("Invalid image reference: %q", c.Image)
+	}
+	return nil
+}
+
+// containerEngines says, per engine, whether it is installed and which
+// methods it supports here
+func containerEngines() map[string]map[string]bool {
+	engines := map[string]map[string]bool{}
+	for _, engine := range []string{"docker", "podman"} {
+		installed := hasCommand(engine)
+		info := map[string]bool{"installed": installed, "run": installed}
+		if installed {
+			_, err := runCommand(engine, "compose", "version")
+			info["compose"] = err == nil
+		}
+		if engine == "podman" && installed {
+			_, err := os.Stat("/run/systemd/system")
+			info["quadlet"] = err == nil && quadletAvailable()
+		}
+		engines[engine] = info
+	}
+	return engines
+}
+
+// quadletAvailable looks for podman's systemd generator, which ships with
+// podman 4.4 and later
+func quadletAvailable() bool {
+	for _, p := range []string{"/usr/lib/systemd/system-generators/podman-system-generator", "/usr/libexec/podman/quadlet"} {
+		if fileExists(p) {
+			return true
+		}
+	}
+	return false
+}
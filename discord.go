@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const discordAPI = "https://discord.com/api/v10"
+
+// Permissions the bot needs in a guild: view channels, send messages,
+// embed links, attach files, read message history and add reactions.
+const discordPermissions = 1024 | 2048 | 16384 | 32768 | 65536 | 64
+
+// Application flags that mean the privileged Message Content intent is on.
+// Without it the bot receives empty messages in guild channels.
+const (
+	discordFlagMessageContent        = 1 << 18
+	discordFlagMessageContentLimited = 1 << 19
+)
+
+// ── Discord ──────────────────────────────────────────────────────────────────
+
+func handleValidateDiscord(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	token := strings.TrimSpace(r.FormValue("token"))
+	if token == "" {
+		errorResponse(w, "token is required")
+		return
+	}
+
+	bot, err := getDiscordBot(token)
+	if err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+
+	rt := currentRuntime()
+	cfg := readConfig(rt)
+	if cfg.Channels == nil {
+		cfg.Channels = make(map[string]map[string]interface{})
+	}
+	dc := cfg.Channels["discord"]
+	if dc == nil {
+		dc = make(map[string]interface{})
+	}
+	dc["enabled"] = true
+	dc["token"] = token
+	cfg.Channels["discord"] = dc
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
+
+	msg := "Bot " + bot.Username + " connected"
+	if !bot.MessageContent {
+		msg += " — enable the Message Content intent in the Developer Portal so it can read messages"
+	}
+	okResponse(w, msg, map[string]interface{}{
+		"username":        bot.Username,
+		"bot_id":          bot.ID,
+		"invite_url":      discordInviteURL(bot.ID),
+		"message_content": bot.MessageContent,
+	})
+}
+
+func handleDiscordGuilds(w http.ResponseWriter, r *http.Request) {
+	token := discordToken()
+	if token == "" {
+		errorResponse(w, "Discord not configured yet")
+		return
+	}
+
+	guilds, err := listDiscordGuilds(token)
+	if err != nil {
+		errorResponse(w, "Failed to list servers: "+err.Error())
+		return
+	}
+	jsonResponse(w, map[string]interface{}{
+		"ok":     true,
+		"guilds": guilds,
+	})
+}
+
+func handleSaveDiscordUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+
+	users := splitList(r.FormValue("user_ids"))
+	if len(users) == 0 {
+		errorResponse(w, "user_ids is required")
+		return
+	}
+	for _, u := range users {
+		if _, err := strconv.ParseUint(u, 10, 64); err != nil {
+			errorResponse(w, "Not a Discord user ID: "+u)
+			return
+		}
+	}
+
+	rt := currentRuntime()
+	cfg := readConfig(rt)
+	if cfg.Channels == nil {
+		cfg.Channels = make(map[string]map[string]interface{})
+	}
+	if cfg.Channels["discord"] == nil {
+		cfg.Channels["discord"] = make(map[string]interface{})
+	}
+	cfg.Channels["discord"]["allowFrom"] = users
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
+	okResponse(w, fmt.Sprintf("%d user ID(s) saved", len(users)), nil)
+}
+
+func handlePingDiscord(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	userID := strings.TrimSpace(r.FormValue("user_id"))
+	if userID == "" {
+		errorResponse(w, "user_id is required")
+		return
+	}
+
+	token := discordToken()
+	if token == "" {
+		errorResponse(w, "No token found — complete token validation first")
+		return
+	}
+
+	ok, msg := sendDiscordPing(token, userID)
+	jsonResponse(w, map[string]interface{}{
+		"ok":      ok,
+		"message": msg,
+	})
+}
+
+func discordToken() string {
+	cfg := readConfig(currentRuntime())
+	token, _ := cfg.Channels["discord"]["token"].(string)
+	return token
+}
+
+// splitList turns a comma/space/newline separated form value into a
+// de-duplicated list of non-empty entries.
+func splitList(s string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	}) {
+		if !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// splitLines is splitList for entries that may contain spaces themselves,
+// such as "+1 555 123 4567": only commas and newlines separate them.
+func splitLines(s string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	}) {
+		f = strings.TrimSpace(f)
+		if f != "" && !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// ── Discord API ──────────────────────────────────────────────────────────────
+
+type DiscordBot struct {
+	ID             string
+	Username       string
+	MessageContent bool
+}
+
+type DiscordGuild struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func discordRequest(method, token, path string, body interface{}, out interface{}) error {
+	var rd io.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		rd = strings.NewReader(string(b))
+	}
+	req, _ := http.NewRequest(method, discordAPI+path, rd)
+	req.Header.Set("Authorization", "Bot "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Connection failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 401 {
+		return fmt.Errorf("Invalid bot token")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		b, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(b, &e) == nil && e.Message != "" {
+			return fmt.Errorf("Discord error %d: %s", resp.StatusCode, e.Message)
+		}
+		return fmt.Errorf("Discord error %d: %s", resp.StatusCode, truncate(string(b), 120))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+func getDiscordBot(token string) (DiscordBot, error) {
+	var me struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Bot      bool   `json:"bot"`
+	}
+	if err := discordRequest("GET", token, "/users/@me", nil, &me); err != nil {
+		return DiscordBot{}, err
+	}
+	if !me.Bot {
+		return DiscordBot{}, fmt.Errorf("That token belongs to a user account, not a bot")
+	}
+
+	bot := DiscordBot{ID: me.ID, Username: me.Username}
+	var app struct {
+		Flags int `json:"flags"`
+	}
+	if err := discordRequest("GET", token, "/applications/@me", nil, &app); err == nil {
+		bot.MessageContent = app.Flags&(discordFlagMessageContent|discordFlagMessageContentLimited) != 0
+	}
+	return bot, nil
+}
+
+func discordInviteURL(clientID string) string {
+	q := url.Values{}
+	q.Set("client_id", clientID)
+	q.Set("scope", "bot applications.commands")
+	q.Set("permissions", strconv.Itoa(discordPermissions))
+	return "https://discord.com/oauth2/authorize?" + q.Encode()
+}
+
+func listDiscordGuilds(token string) ([]DiscordGuild, error) {
+	var guilds []DiscordGuild
+	err := discordRequest("GET", token, "/users/@me/guilds", nil, &guilds)
+	return guilds, err
+}
+
+func sendDiscordPing(token, userID string) (bool, string) {
+	var dm struct {
+		ID string `json:"id"`
+	}
+	err := discordRequest("POST", token, "/users/@me/channels",
+		map[string]string{"recipient_id": userID}, &dm)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	err = discordRequest("POST", token, "/channels/"+dm.ID+"/messages",
+		map[string]string{"content": "🟢 Ping from claw-setup!\n\nYour PicoClaw agent is configured and ready."}, nil)
+	if err != nil {
+		return false, err.Error() + " (do you share a server with the bot?)"
+	}
+	return true, "Ping sent — check your Discord DMs"
+}
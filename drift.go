@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ServiceDrift compares the installed unit/script/plist with what the wizard
+// would write today. Diff is line based: "- " installed only, "+ " generated
+// only, "  " unchanged.
+type ServiceDrift struct {
+	Installed bool     `json:"installed"`
+	Drifted   bool     `json:"drifted"`
+	Path      string   `json:"path"`
+	Reason    string   `json:"reason,omitempty"`
+	Diff      []string `json:"diff,omitempty"`
+}
+
+// Where each backend names the binary it runs: ExecStart= / command= lines,
+// runit's chpst line, or the first launchd ProgramArguments entry.
+var execPathPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?m)^(?:ExecStart|command)=['"]?([^\s'"]+)`),
+	regexp.MustCompile(`(?m)^exec chpst (?:-e \S+ )?-u \S+ '?([^\s']+)`),
+	regexp.MustCompile(`<key>ProgramArguments</key>\s*<array>\s*<string>([^<]+)</string>`),
+}
+
+// Environment lines carry env-file values inline (launchd, supervisord), so
+// the diff only ever shows their keys
+var (
+	systemdEnvPattern     = regexp.MustCompile(`^Environment="?([A-Za-z_][A-Za-z0-9_]*)=`)
+	supervisordEnvPattern = regexp.MustCompile(`(?:^environment=|,)([A-Za-z_][A-Za-z0-9_]*)="`)
+	plistStringPattern    = regexp.MustCompile(`<string>.*</string>`)
+)
+
+const maskedValue = "********"
+
+// ── Drift ────────────────────────────────────────────────────────────────────
+
+func handleServiceDrift(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, checkServiceDrift(detectServiceManager()))
+}
+
+// handleRepairService rewrites the service definition from the current spec
+// and restarts the agent so the fix takes effect.
+func handleRepairService(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mgr := detectServiceManager()
+	spec, err := defaultServiceSpec()
+	if err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+	if err := mgr.Install(spec); err != nil {
+		errorResponse(w, "Repair failed: "+err.Error())
+		return
+	}
+	if err := mgr.Restart(); err != nil {
+		errorResponse(w, "Service file repaired but restart failed: "+err.Error())
+		return
+	}
+	okResponse(w, "Service repaired and restarted", nil)
+}
+
+func checkServiceDrift(mgr ServiceManager) ServiceDrift {
+	spec, specErr := defaultServiceSpec()
+	path, want := mgr.Definition(spec)
+	d := ServiceDrift{Path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return d
+	}
+	d.Installed = true
+	if err != nil {
+		d.Reason = "Could not read " + path + ": " + err.Error()
+		return d
+	}
+	have := string(data)
+
+	current := definedExecPath(have)
+	if current != "" {
+		if _, err := os.Stat(current); err != nil {
+			d.Drifted = true
+			d.Reason = fmt.Sprintf("The service runs %s, which no longer exists", current)
+		} else if specErr == nil && current != spec.ExecPath {
+			d.Drifted = true
+			d.Reason = fmt.Sprintf("The service runs %s, but %s is now at %s", current, currentRuntime().Binary(), spec.ExecPath)
+		}
+	}
+	// Without the agent in PATH there is nothing sensible to compare against
+	if specErr != nil {
+		if d.Reason == "" {
+			d.Reason = specErr.Error()
+		}
+		return d
+	}
+
+	if have != want {
+		d.Drifted = true
+		masked, maskedWant := maskServiceEnv(have), maskServiceEnv(want)
+		d.Diff = lineDiff(masked, maskedWant)
+		if d.Reason == "" && masked == maskedWant {
+			d.Reason = "The service's environment values differ from what the wizard would generate now"
+		} else if d.Reason == "" {
+			d.Reason = "The service file differs from what the wizard would generate now"
+		}
+	}
+	return d
+}
+
+// ── Drift Helpers ────────────────────────────────────────────────────────────
+
+func definedExecPath(content string) string {
+	for _, re := range execPathPatterns {
+		if m := re.FindStringSubmatch(content); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// maskServiceEnv replaces environment values with a placeholder, keeping
+// the keys so an added or removed variable still shows in the diff
+func maskServiceEnv(content string) string {
+	lines := strings.Split(content, "\n")
+	inPlistEnv := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "<key>EnvironmentVariables</key>":
+			inPlistEnv = true
+		case inPlistEnv && trimmed == "</dict>":
+			inPlistEnv = false
+		case inPlistEnv:
+			lines[i] = plistStringPattern.ReplaceAllString(line, "<string>"+maskedValue+"</string>")
+		case strings.HasPrefix(line, "Environment="):
+			if m := systemdEnvPattern.FindStringSubmatch(line); m != nil {
+				lines[i] = "Environment=" + m[1] + "=" + maskedValue
+			}
+		case strings.HasPrefix(line, "environment="):
+			var pairs []string
+			for _, m := range supervisordEnvPattern.FindAllStringSubmatch(line, -1) {
+				pairs = append(pairs, m[1]+"="+maskedValue)
+			}
+			lines[i] = "environment=" + strings.Join(pairs, ",")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lineDiff is a plain LCS diff — service files are a few dozen lines, so
+// the quadratic table is nothing.
+func lineDiff(a, b string) []string {
+	x := strings.Split(strings.TrimRight(a, "\n"), "\n")
+	y := strings.Split(strings.TrimRight(b, "\n"), "\n")
+
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			out = append(out, "  "+x[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+x[i])
+			i++
+		default:
+			out = append(out, "+ "+y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		out = append(out, "- "+x[i])
+	}
+	for ; j < len(y); j++ {
+		out = append(out, "+ "+y[j])
+	}
+	return out
+}
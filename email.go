@@ -0,0 +1,467 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const emailTimeout = 15 * time.Second
+
+// EmailSettings is everything needed to reach a mailbox over IMAP and send
+// from it over SMTP. Security is one of "tls", "starttls" or "none"; an
+// empty one means encrypted, and "none" is only allowed to localhost.
+type EmailSettings struct {
+	Address      string
+	Username     string
+	Password     string
+	IMAPHost     string
+	IMAPPort     int
+	IMAPSecurity string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPSecurity string
+}
+
+// ── Email ────────────────────────────────────────────────────────────────────
+
+func handleValidateEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+
+	es := EmailSettings{
+		Address:      strings.TrimSpace(r.FormValue("address")),
+		Username:     strings.TrimSpace(r.FormValue("username")),
+		Password:     r.FormValue("password"),
+		IMAPHost:     strings.TrimSpace(r.FormValue("imap_host")),
+		IMAPSecurity: r.FormValue("imap_security"),
+		SMTPHost:     strings.TrimSpace(r.FormValue("smtp_host")),
+		SMTPSecurity: r.FormValue("smtp_security"),
+	}
+	es.IMAPPort, _ = strconv.Atoi(r.FormValue("imap_port"))
+	es.SMTPPort, _ = strconv.Atoi(r.FormValue("smtp_port"))
+	if es.Username == "" {
+		es.Username = es.Address
+	}
+
+	// No password supplied — fall back to whatever is already saved in config
+	if es.Password == "" {
+		es.Password = emailSettingsFromConfig().Password
+	}
+
+	if _, err := mail.ParseAddress(es.Address); err != nil {
+		errorResponse(w, "Invalid email address")
+		return
+	}
+	if es.IMAPHost == "" || es.SMTPHost == "" || es.Password == "" {
+		errorResponse(w, "IMAP host, SMTP host and password are required")
+		return
+	}
+	es.IMAPSecurity = defaultSecurity(es.IMAPSecurity, es.IMAPPort, 993)
+	es.SMTPSecurity = defaultSecurity(es.SMTPSecurity, es.SMTPPort, 465)
+	es.IMAPPort = defaultPort(es.IMAPPort, es.IMAPSecurity, 993, 143)
+	es.SMTPPort = defaultPort(es.SMTPPort, es.SMTPSecurity, 465, 587)
+
+	folders, err := testIMAP(es)
+	if err != nil {
+		errorResponse(w, "IMAP: "+err.Error())
+		return
+	}
+	if err := testSMTP(es); err != nil {
+		errorResponse(w, "SMTP: "+err.Error())
+		return
+	}
+
+	rt := currentRuntime()
+	cfg := readConfig(rt)
+	if cfg.Channels == nil {
+		cfg.Channels = make(map[string]map[string]interface{})
+	}
+	em := cfg.Channels["email"]
+	if em == nil {
+		em = make(map[string]interface{})
+	}
+	em["enabled"] = true
+	em["address"] = es.Address
+	em["username"] = es.Username
+	em["password"] = es.Password
+	em["imap_host"] = es.IMAPHost
+	em["imap_port"] = es.IMAPPort
+	em["imap_security"] = es.IMAPSecurity
+	em["smtp_host"] = es.SMTPHost
+	em["smtp_port"] = es.SMTPPort
+	em["smtp_security"] = es.SMTPSecurity
+	if _, ok := em["folder"]; !ok {
+		em["folder"] = "INBOX"
+	}
+	cfg.Channels["email"] = em
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
+
+	okResponse(w, fmt.Sprintf("IMAP and SMTP login OK — %d folder(s) found", len(folders)), map[string]interface{}{
+		"folders": folders,
+	})
+}
+
+func handleSaveEmailSenders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+
+	var senders []string
+	// "Name <addr>" entries contain spaces, so only commas and newlines split
+	for _, s := range splitLines(r.FormValue("senders")) {
+		addr, err := mail.ParseAddress(s)
+		if err != nil {
+			errorResponse(w, "Not an email address: "+s)
+			return
+		}
+		senders = append(senders, strings.ToLower(addr.Address))
+	}
+	if len(senders) == 0 {
+		errorResponse(w, "senders is required")
+		return
+	}
+	folder := strings.TrimSpace(r.FormValue("folder"))
+
+	rt := currentRuntime()
+	cfg := readConfig(rt)
+	if cfg.Channels == nil {
+		cfg.Channels = make(map[string]map[string]interface{})
+	}
+	if cfg.Channels["email"] == nil {
+		cfg.Channels["email"] = make(map[string]interface{})
+	}
+	cfg.Channels["email"]["allowFrom"] = senders
+	if folder != "" {
+		cfg.Channels["email"]["folder"] = folder
+	}
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
+	okResponse(w, fmt.Sprintf("%d sender(s) saved", len(senders)), nil)
+}
+
+func handlePingEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	to, err := mail.ParseAddress(strings.TrimSpace(r.FormValue("to")))
+	if err != nil {
+		errorResponse(w, "Invalid recipient address")
+		return
+	}
+
+	es := emailSettingsFromConfig()
+	if es.SMTPHost == "" {
+		errorResponse(w, "Email not configured yet")
+		return
+	}
+
+	body := "🟢 Ping from claw-setup!\r\n\r\nYour PicoClaw agent is configured and ready.\r\nReply to this address to talk to your twin.\r\n"
+	if err := sendEmail(es, to.Address, "Ping from claw-setup", body); err != nil {
+		errorResponse(w, "Send failed: "+err.Error())
+		return
+	}
+	okResponse(w, "Test mail sent to "+to.Address+" — check your inbox (and spam)", nil)
+}
+
+func emailSettingsFromConfig() EmailSettings {
+	cfg := readConfig(currentRuntime())
+	em := cfg.Channels["email"]
+	es := EmailSettings{}
+	es.Address, _ = em["address"].(string)
+	es.Username, _ = em["username"].(string)
+	es.Password, _ = em["password"].(string)
+	es.IMAPHost, _ = em["imap_host"].(string)
+	es.IMAPSecurity, _ = em["imap_security"].(string)
+	es.SMTPHost, _ = em["smtp_host"].(string)
+	es.SMTPSecurity, _ = em["smtp_security"].(string)
+	// JSON numbers decode as float64
+	if p, ok := em["imap_port"].(float64); ok {
+		es.IMAPPort = int(p)
+	}
+	if p, ok := em["smtp_port"].(float64); ok {
+		es.SMTPPort = int(p)
+	}
+	es.IMAPSecurity = defaultSecurity(es.IMAPSecurity, es.IMAPPort, 993)
+	es.SMTPSecurity = defaultSecurity(es.SMTPSecurity, es.SMTPPort, 465)
+	return es
+}
+
+// defaultSecurity never lets a missing setting mean plaintext: implicit TLS
+// on the TLS port, STARTTLS anywhere else
+func defaultSecurity(security string, port, tlsPort int) string {
+	switch security {
+	case "tls", "starttls", "none":
+		return security
+	}
+	if port == tlsPort {
+		return "tls"
+	}
+	return "starttls"
+}
+
+// checkPlaintext refuses to send a password unencrypted to anything but
+// this machine, e.g. a local bridge such as Proton Mail Bridge
+func checkPlaintext(security, host string) error {
+	if security != "none" {
+		return nil
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("Security \"None\" would send your password unencrypted to %s — choose TLS or STARTTLS (None is only allowed for localhost)", host)
+}
+
+func defaultPort(port int, security string, tlsPort, plainPort int) int {
+	if port > 0 {
+		return port
+	}
+	if security == "tls" {
+		return tlsPort
+	}
+	return plainPort
+}
+
+// ── SMTP ─────────────────────────────────────────────────────────────────────
+
+func dialSMTP(es EmailSettings) (*smtp.Client, error) {
+	if err := checkPlaintext(es.SMTPSecurity, es.SMTPHost); err != nil {
+		return nil, err
+	}
+	addr := net.JoinHostPort(es.SMTPHost, strconv.Itoa(es.SMTPPort))
+	tlsConfig := &tls.Config{ServerName: es.SMTPHost}
+
+	var conn net.Conn
+	var err error
+	if es.SMTPSecurity == "tls" {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: emailTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, emailTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Connection failed: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(emailTimeout))
+
+	c, err := smtp.NewClient(conn, es.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if es.SMTPSecurity == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			c.Close()
+			return nil, fmt.Errorf("server does not offer STARTTLS")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("STARTTLS failed: %v", err)
+		}
+	}
+	if ok, _ := c.Extension("AUTH"); ok {
+		if err := c.Auth(smtp.PlainAuth("", es.Username, es.Password, es.SMTPHost)); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("Login failed: %v", err)
+		}
+	}
+	return c, nil
+}
+
+func testSMTP(es EmailSettings) error {
+	c, err := dialSMTP(es)
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func sendEmail(es EmailSettings, to, subject, body string) error {
+	c, err := dialSMTP(es)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Mail(es.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	wc, err := c.Data()
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s",
+		es.Address, to, subject, time.Now().Format(time.RFC1123Z), body)
+	if _, err := wc.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err := wc.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// ── IMAP ─────────────────────────────────────────────────────────────────────
+
+// Just enough IMAP4rev1 to log in and list folders — the agent itself does
+// the real mailbox work.
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+var imapListPattern = regexp.MustCompile(`^\* LIST \([^)]*\) (?:NIL|"(?:[^"\\]|\\.)*") (.+)$`)
+
+func dialIMAP(es EmailSettings) (*imapConn, error) {
+	if err := checkPlaintext(es.IMAPSecurity, es.IMAPHost); err != nil {
+		return nil, err
+	}
+	addr := net.JoinHostPort(es.IMAPHost, strconv.Itoa(es.IMAPPort))
+	tlsConfig := &tls.Config{ServerName: es.IMAPHost}
+
+	var conn net.Conn
+	var err error
+	if es.IMAPSecurity == "tls" {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: emailTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, emailTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Connection failed: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(emailTimeout))
+
+	c := &imapConn{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readLine()
+	if err != nil || !strings.HasPrefix(greeting, "* OK") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected greeting: %s", truncate(greeting, 80))
+	}
+
+	if es.IMAPSecurity == "starttls" {
+		if _, err := c.command("STARTTLS"); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("STARTTLS failed: %v", err)
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake failed: %v", err)
+		}
+		c.conn = tlsConn
+		c.r = bufio.NewReader(tlsConn)
+	}
+	return c, nil
+}
+
+func (c *imapConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	line = strings.TrimRight(line, "\r\n")
+	// Inline literal {n}: read the n bytes that follow and splice them in
+	if strings.HasSuffix(line, "}") {
+		if i := strings.LastIndex(line, "{"); i >= 0 {
+			if n, convErr := strconv.Atoi(line[i+1 : len(line)-1]); convErr == nil {
+				buf := make([]byte, n)
+				if _, err := io.ReadFull(c.r, buf); err != nil {
+					return line, err
+				}
+				rest, err := c.readLine()
+				return line[:i] + strconv.Quote(string(buf)) + rest, err
+			}
+		}
+	}
+	return line, err
+}
+
+// command sends a tagged command and returns the untagged lines before the
+// tagged completion, or an error if the server answers NO or BAD.
+func (c *imapConn) command(cmd string) ([]string, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%d", c.tag)
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, cmd); err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return lines, err
+		}
+		if strings.HasPrefix(line, tag+" ") {
+			status := strings.TrimPrefix(line, tag+" ")
+			if strings.HasPrefix(status, "OK") {
+				return lines, nil
+			}
+			return lines, fmt.Errorf("%s", status)
+		}
+		lines = append(lines, line)
+	}
+}
+
+func (c *imapConn) close() {
+	c.command("LOGOUT")
+	c.conn.Close()
+}
+
+func imapQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func testIMAP(es EmailSettings) ([]string, error) {
+	c, err := dialIMAP(es)
+	if err != nil {
+		return nil, err
+	}
+	defer c.close()
+
+	if _, err := c.command("LOGIN " + imapQuote(es.Username) + " " + imapQuote(es.Password)); err != nil {
+		return nil, fmt.Errorf("Login failed: %v", err)
+	}
+
+	lines, err := c.command(`LIST "" "*"`)
+	if err != nil {
+		return nil, fmt.Errorf("Could not list folders: %v", err)
+	}
+	var folders []string
+	for _, line := range lines {
+		m := imapListPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		name := m[1]
+		if unq, err := strconv.Unquote(name); err == nil {
+			name = unq
+		}
+		folders = append(folders, name)
+	}
+	return folders, nil
+}
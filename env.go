@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// EnvVar is one service environment variable as shown in the editor.
+// Secret values are masked before they leave the server.
+type EnvVar struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret"`
+}
+
+var (
+	envKeyPattern    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	envSecretPattern = regexp.MustCompile(`(?i)(KEY|TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|AUTH)`)
+)
+
+func envFilePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".picoclaw", "picoclaw.env")
+}
+
+// ── Environment ──────────────────────────────────────────────────────────────
+
+// handleServiceEnv lists the variables on GET. POST replaces the whole set
+// from parallel key/value fields; a value sent back still masked keeps the
+// stored secret.
+func handleServiceEnv(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResponse(w, map[string]interface{}{
+			"vars": maskedEnv(readEnvFile(envFilePath())),
+			"path": envFilePath(),
+		})
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+
+	keys, values := r.Form["key"], r.Form["value"]
+	if len(keys) != len(values) {
+		errorResponse(w, "Mismatched keys and values")
+		return
+	}
+	old := readEnvFile(envFilePath())
+	env := map[string]string{}
+	for i, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if !envKeyPattern.MatchString(key) {
+			errorResponse(w, fmt.Sprintf("%q is not a valid variable name", key))
+			return
+		}
+		if key == "HOME" {
+			errorResponse(w, "HOME is set by the service and can't be overridden")
+			return
+		}
+		if _, dup := env[key]; dup {
+			errorResponse(w, key+" is listed twice")
+			return
+		}
+		value := values[i]
+		if prev, ok := old[key]; ok && envSecretPattern.MatchString(key) && value == maskEnvValue(prev) {
+			value = prev
+		}
+		if strings.ContainsAny(value, "'\n") {
+			errorResponse(w, key+": values can't contain single quotes or newlines")
+			return
+		}
+		env[key] = value
+	}
+
+	if err := writeEnvFile(envFilePath(), env); err != nil {
+		errorResponse(w, "Failed to save environment: "+err.Error())
+		return
+	}
+
+	msg, err := applyServiceChange()
+	if err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+	okResponse(w, msg, map[string]interface{}{"vars": maskedEnv(env)})
+}
+
+// applyServiceChange regenerates an installed service and restarts it, so
+// settings saved from the wizard take effect straight away.
+func applyServiceChange() (string, error) {
+	mgr := detectServiceManager()
+	spec, err := defaultServiceSpec()
+	if err != nil {
+		return "Saved — applied when the service is installed", nil
+	}
+	if path, _ := mgr.Definition(spec); !fileExists(path) {
+		return "Saved — applied when the service is installed", nil
+	}
+	if err := mgr.Install(spec); err != nil {
+		return "", err
+	}
+	if err := mgr.Restart(); err != nil {
+		return "", fmt.Errorf("Saved but restart failed: %v", err)
+	}
+	return "Saved and applied — agent restarted", nil
+}
+
+// ── Environment Helpers ──────────────────────────────────────────────────────
+
+// readEnvFile parses KEY=value lines, as written by writeEnvFile and
+// understood by systemd's EnvironmentFile= and a POSIX shell alike.
+func readEnvFile(path string) map[string]string {
+	env := map[string]string{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return env
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[strings.TrimSpace(key)] = value
+	}
+	return env
+}
+
+// writeEnvFile always single-quotes values (callers reject ' and newlines),
+// which systemd and sh both take literally. The file holds secrets, so 0600.
+func writeEnvFile(path string, env map[string]string) error {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString("# Managed by claw-setup — service environment for picoclaw\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s='%s'\n", k, env[k])
+	}
+
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0600)
+}
+
+func maskedEnv(env map[string]string) []EnvVar {
+	vars := make([]EnvVar, 0, len(env))
+	for k, v := range env {
+		secret := envSecretPattern.MatchString(k)
+		if secret {
+			v = maskEnvValue(v)
+		}
+		vars = append(vars, EnvVar{Key: k, Value: v, Secret: secret})
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Key < vars[j].Key })
+	return vars
+}
+
+// maskEnvValue hides a secret variable entirely. Unlike maskSecret's API
+// key prefix, only the last 4 characters of a long value show, and the
+// mask is the same width whatever the length.
+func maskEnvValue(v string) string {
+	if len(v) < 16 {
+		return "********"
+	}
+	return "********" + v[len(v)-4:]
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -1,12 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
 )
 
@@ -29,10 +29,11 @@ func handleValidateLLM(w http.ResponseWriter, r *http.Request) {
 		errorResponse(w, "provider and model are required")
 		return
 	}
+	rt := currentRuntime()
 
 	// No key supplied — fall back to whatever is already saved in config
 	if apiKey == "" {
-		cfg := readConfig()
+		cfg := readConfig(rt)
 		if p, ok := cfg.Providers[provider]; ok {
 			apiKey, _ = p["api_key"].(string)
 		}
@@ -44,7 +45,7 @@ func handleValidateLLM(w http.ResponseWriter, r *http.Request) {
 
 	ok, msg := validateLLMKey(provider, apiKey, model)
 	if ok {
-		cfg := readConfig()
+		cfg := readConfig(rt)
 		if cfg.Providers == nil {
 			cfg.Providers = make(map[string]map[string]interface{})
 		}
@@ -60,7 +61,10 @@ func handleValidateLLM(w http.ResponseWriter, r *http.Request) {
 		cfg.Agents["defaults"] = map[string]interface{}{
 			"model": model,
 		}
-		writeConfig(cfg)
+		if err := writeConfig(rt, cfg); err != nil {
+			errorResponse(w, "Failed to save config: "+err.Error())
+			return
+		}
 	}
 	jsonResponse(w, map[string]interface{}{
 		"ok":      ok,
@@ -84,7 +88,8 @@ func handleValidateTelegram(w http.ResponseWriter, r *http.Request) {
 
 	ok, msg, username := validateTelegramToken(token)
 	if ok {
-		cfg := readConfig()
+		rt := currentRuntime()
+		cfg := readConfig(rt)
 		if cfg.Channels == nil {
 			cfg.Channels = make(map[string]map[string]interface{})
 		}
@@ -92,7 +97,10 @@ func handleValidateTelegram(w http.ResponseWriter, r *http.Request) {
 			"enabled": true,
 			"token":   token,
 		}
-		writeConfig(cfg)
+		if err := writeConfig(rt, cfg); err != nil {
+			errorResponse(w, "Failed to save config: "+err.Error())
+			return
+		}
 	}
 	jsonResponse(w, map[string]interface{}{
 		"ok":       ok,
@@ -113,7 +121,8 @@ func handleSaveTelegramUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg := readConfig()
+	rt := currentRuntime()
+	cfg := readConfig(rt)
 	if cfg.Channels == nil {
 		cfg.Channels = make(map[string]map[string]interface{})
 	}
@@ -121,7 +130,10 @@ func handleSaveTelegramUser(w http.ResponseWriter, r *http.Request) {
 		cfg.Channels["telegram"] = make(map[string]interface{})
 	}
 	cfg.Channels["telegram"]["allowFrom"] = []string{userID}
-	writeConfig(cfg)
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
 	okResponse(w, "User ID saved", nil)
 }
 
@@ -137,7 +149,7 @@ func handlePingTelegram(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg := readConfig()
+	cfg := readConfig(currentRuntime())
 	tg := cfg.Channels["telegram"]
 	if tg == nil {
 		errorResponse(w, "Telegram not configured yet")
@@ -195,7 +207,8 @@ func handleSaveSoul(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	soulPath := getSoulPath()
+	rt := currentRuntime()
+	soulPath := getSoulPath(rt)
 	os.MkdirAll(filepath.Dir(soulPath), 0755)
 	err := os.WriteFile(soulPath, []byte(content), 0644)
 	if err != nil {
@@ -213,55 +226,48 @@ func handleInstallService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ok, msg := installSystemdService()
+	r.ParseMultipartForm(10 << 20)
+
+	mgr := detectServiceManager()
+	// Fallback for when lingering can't be enabled: a system unit with
+	// User= starts at boot without a login session
+	if m, ok := mgr.(systemdManager); ok && m.user && r.FormValue("mode") == "system" {
+		// Stop the user unit so the two don't fight over the gateway port,
+		// but only remove it once the system unit is up
+		m.Stop()
+		ok, msg := installService(systemdManager{})
+		if !ok {
+			m.Start()
+			errorResponse(w, msg)
+			return
+		}
+		if err := m.Uninstall(); err != nil {
+			errorResponse(w, "System service started, but removing the user service failed: "+err.Error())
+			return
+		}
+		okResponse(w, msg, nil)
+		return
+	}
+
+	ok, msg := installService(mgr)
 	jsonResponse(w, map[string]interface{}{
 		"ok":      ok,
 		"message": msg,
 	})
 }
 
-func installSystemdService() (bool, string) {
-	picocławPath, err := exec.LookPath("picoclaw")
+func installService(mgr ServiceManager) (bool, string) {
+	spec, err := defaultServiceSpec()
 	if err != nil {
-		return false, "picoclaw not found in PATH"
-	}
-
-	home, _ := os.UserHomeDir()
-	serviceDir := filepath.Join(home, ".config", "systemd", "user")
-	os.MkdirAll(serviceDir, 0755)
-
-	serviceContent := fmt.Sprintf(`[Unit]
-Description=PicoClaw AI Agent
-After=network.target
-
-[Service]
-Type=simple
-ExecStart=%s gateway
-Restart=on-failure
-RestartSec=5
-WorkingDirectory=%s
-Environment=HOME=%s
-
-[Install]
-WantedBy=default.target
-`, picocławPath, home, home)
-
-	servicePath := filepath.Join(serviceDir, "picoclaw.service")
-	if err := os.WriteFile(servicePath, []byte(serviceContent), 0644); err != nil {
-		return false, "Failed to write service file: " + err.Error()
+		return false, err.Error()
 	}
-
-	commands := [][]string{
-		{"systemctl", "--user", "daemon-reload"},
-		{"systemctl", "--user", "enable", "picoclaw"},
-		{"systemctl", "--user", "start", "picoclaw"},
+	if err := mgr.Install(spec); err != nil {
+		return false, err.Error()
 	}
-	for _, cmd := range commands {
-		if out, err := exec.Command(cmd[0], cmd[1:]...).CombinedOutput(); err != nil {
-			return false, strings.TrimSpace(string(out))
-		}
+	if err := mgr.Start(); err != nil {
+		return false, err.Error()
 	}
-	return true, "Service installed and started"
+	return true, "Service installed and started (" + mgr.Name() + ")"
 }
 
 // ── Restart ──────────────────────────────────────────────────────────────────
@@ -272,25 +278,26 @@ func handleRestartService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var out string
-	var err error
-	if runtime.GOOS == "darwin" {
-		plistPath := os.ExpandEnv("$HOME/Library/LaunchAgents/com.picoclaw.agent.plist")
-		exec.Command("launchctl", "unload", plistPath).Run()
-		out, err = runCommand("launchctl", "load", plistPath)
-	} else {
-		out, err = runCommand("systemctl", "--user", "restart", "picoclaw")
+	if err := detectServiceManager().Restart(); err != nil {
+		errorResponse(w, "Restart failed: "+err.Error())
+		return
 	}
+	okResponse(w, "Agent restarted", nil)
+}
 
-	if err != nil {
-		msg := strings.TrimSpace(out)
-		if msg == "" {
-			msg = "Restart failed: " + err.Error()
-		}
-		errorResponse(w, msg)
+// ── Linger ───────────────────────────────────────────────────────────────────
+
+func handleEnableLinger(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
 		return
 	}
-	okResponse(w, "Agent restarted", nil)
+
+	if err := enableLinger(); err != nil {
+		errorResponse(w, "Could not enable lingering: "+err.Error())
+		return
+	}
+	okResponse(w, "Lingering enabled — the agent now starts at boot without a login", nil)
 }
 
 // ── Local IP ─────────────────────────────────────────────────────────────────
@@ -305,7 +312,7 @@ func handleLocalIP(w http.ResponseWriter, r *http.Request) {
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	status := buildSystemStatus()
-	cfg := readConfig()
+	cfg := readConfig(currentRuntime())
 
 	// Get current model
 	model := ""
@@ -344,67 +351,72 @@ func handleInstallPicoclaw(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Detect architecture
-	out, err := runCommand("uname", "-m")
-	if err != nil {
-		errorResponse(w, "Could not detect architecture")
+	r.ParseMultipartForm(10 << 20)
+	target := r.FormValue("target")
+	if target != "" && target != "system" && target != "user" {
+		errorResponse(w, "Unknown install target: "+target)
 		return
 	}
 
-	var picoArch string
-	switch strings.TrimSpace(out) {
-	case "aarch64":
-	    picoArch = "arm64"
-	case "armv7l":
-	    picoArch = "arm"
-	case "x86_64":
-	    picoArch = "x86_64"
-	default:
-	    errorResponse(w, "Unsupported architecture: "+out)
-	    return
+	// The UI already greys the button out; this catches scripted installs.
+	// Network checks are left to the download, which reports them itself.
+	for _, req := range preflight(false) {
+		if req.Status == "fail" {
+			errorResponse(w, req.Name+": "+req.Detail+" — "+req.Fix)
+			return
+		}
 	}
 
-	tarName := "picoclaw_Linux_" + picoArch + ".tar.gz"
-	url := "https://github.com/sipeed/picoclaw/releases/latest/download/" + tarName
-	tmpTar := "/tmp/" + tarName
-	tmpDir := "/tmp/picoclaw-extract"
-	finalPath := "/usr/local/bin/picoclaw"
-
-	// Download with redirect follow
-	_, err = runCommand("wget", "-L", "-q", "-O", tmpTar, url)
-	if err != nil {
-	    errorResponse(w, "Download failed: "+err.Error())
-	    return
+	// No source means the runtime's own install; upload and media install
+	// a PicoClaw archive offline
+	rt := currentRuntime()
+	install := func(ctx context.Context, j *Job) (string, error) {
+		return rt.Install(ctx, target, j)
+	}
+	cleanup := func() {}
+	// Only an offline install can be checked against nothing better than
+	// a checksum from the same drive
+	offline, verified := false, true
+	if source := r.FormValue("source"); source != "" {
+		if rt.ID() != "picoclaw" {
+			errorResponse(w, "Offline install is only available for PicoClaw")
+			return
+		}
+		var err error
+		offline = true
+		install, cleanup, verified, err = offlineInstall(r, source, target)
+		if err != nil {
+			errorResponse(w, err.Error())
+			return
+		}
 	}
 
-	// Extract
-	os.MkdirAll(tmpDir, 0755)
-	_, err = runCommand("tar", "-xzf", tmpTar, "-C", tmpDir)
-	if err != nil {
-	    errorResponse(w, "Extract failed: "+err.Error())
-	    return
-	}
+	job, err := jobs.start("install", func(ctx context.Context, j *Job) (map[string]interface{}, error) {
+		if _, err := install(ctx, j); err != nil {
+			return nil, err
+		}
 
-	// Find the binary inside extracted folder
-	_, err = runCommand("sudo", "mv", tmpDir+"/picoclaw", finalPath)
+		// Verify
+		path, err := exec.LookPath(rt.Binary())
+		if err != nil || path == "" {
+			return nil, fmt.Errorf("Installed but not found in PATH — restart the wizard")
+		}
+		result := map[string]interface{}{"message": rt.Name() + " installed at " + path}
+		if offline {
+			result["verified"] = verified
+			result["integrity_only"] = !verified
+			if !verified {
+				result["message"] = rt.Name() + " installed at " + path + " — checked only against the checksum on the drive, not verified against the release"
+			}
+		}
+		return result, nil
+	})
 	if err != nil {
-	    // try root of extract dir
-	    errorResponse(w, "Could not find picoclaw binary in archive: "+err.Error())
-	    return
-	}
-
-	// Cleanup
-	os.Remove(tmpTar)
-	os.RemoveAll(tmpDir)
-
-	// Verify
-	path, err := exec.LookPath("picoclaw")
-	if err != nil || path == "" {
-		errorResponse(w, "Installed but not found in PATH — restart the wizard")
+		cleanup()
+		errorResponse(w, err.Error())
 		return
 	}
-
-	okResponse(w, "PicoClaw installed at "+path, nil)
+	okResponse(w, "Install started", map[string]interface{}{"job_id": job.state.ID})
 }
 
 
@@ -422,7 +434,7 @@ func handleGetModels(w http.ResponseWriter, r *http.Request) {
 
 	// No key supplied — fall back to whatever is already saved in config
 	if apiKey == "" {
-		cfg := readConfig()
+		cfg := readConfig(currentRuntime())
 		if p, ok := cfg.Providers[provider]; ok {
 			apiKey, _ = p["api_key"].(string)
 		}
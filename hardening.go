@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ServiceHardening is the resource limit and sandboxing profile written into
+// the systemd unit. Other service managers ignore it.
+type ServiceHardening struct {
+	Enabled         bool   `json:"enabled"`
+	MemoryMax       string `json:"memory_max"`
+	CPUQuota        string `json:"cpu_quota"`
+	NoNewPrivileges bool   `json:"no_new_privileges"`
+	ProtectSystem   string `json:"protect_system"`
+	ProtectHome     bool   `json:"protect_home"`
+	PrivateTmp      bool   `json:"private_tmp"`
+}
+
+var (
+	memoryMaxPattern = regexp.MustCompile(`^(\d+[KMGT]?|\d{1,2}%|100%|infinity)$`)
+	cpuQuotaPattern  = regexp.MustCompile(`^\d+%$`)
+)
+
+// defaultHardening leaves the agent half the RAM and three quarters of the
+// cores, so a runaway tool call can't take a 512MB/1GB Pi down with it.
+func defaultHardening() ServiceHardening {
+	memoryMax := "50%"
+	if total := totalRAMBytes(); total > 0 {
+		memoryMax = fmt.Sprintf("%dM", total/2/(1024*1024))
+	}
+	return ServiceHardening{
+		Enabled:         true,
+		MemoryMax:       memoryMax,
+		CPUQuota:        fmt.Sprintf("%d%%", runtime.NumCPU()*75),
+		NoNewPrivileges: true,
+		ProtectSystem:   "full",
+		ProtectHome:     true,
+		PrivateTmp:      true,
+	}
+}
+
+// hardeningSettings is the saved profile, or the defaults if none was saved
+func hardeningSettings() ServiceHardening {
+	if h := readSettings().Hardening; h != nil {
+		return *h
+	}
+	return defaultHardening()
+}
+
+// unitLines renders the [Service] directives. ProtectHome=read-only would
+// also lock the agent out of its own config and workspace, so those stay
+// writable through ReadWritePaths ("-" so a missing directory isn't fatal).
+// An OpenClaw workspace can live outside the data directory, so it is
+// listed too when it does.
+func (h ServiceHardening) unitLines(dataDir, workspace string) string {
+	if !h.Enabled {
+		return ""
+	}
+	var b strings.Builder
+	if h.MemoryMax != "" {
+		fmt.Fprintf(&b, "MemoryMax=%s\n", h.MemoryMax)
+	}
+	if h.CPUQuota != "" {
+		fmt.Fprintf(&b, "CPUQuota=%s\n", h.CPUQuota)
+	}
+	if h.NoNewPrivileges {
+		b.WriteString("NoNewPrivileges=true\n")
+	}
+	if h.ProtectSystem != "" {
+		fmt.Fprintf(&b, "ProtectSystem=%s\n", h.ProtectSystem)
+	}
+	if h.ProtectHome {
+		b.WriteString("ProtectHome=read-only\n")
+	}
+	if (h.ProtectHome || h.ProtectSystem == "strict") && dataDir != "" {
+		fmt.Fprintf(&b, "ReadWritePaths=-%s\n", dataDir)
+		if workspace != "" && workspace != dataDir && !strings.HasPrefix(workspace, dataDir+"/") {
+			fmt.Fprintf(&b, "ReadWritePaths=-%s\n", workspace)
+		}
+	}
+	if h.PrivateTmp {
+		b.WriteString("PrivateTmp=true\n")
+	}
+	return b.String()
+}
+
+func (h ServiceHardening) validate() error {
+	if !h.Enabled {
+		return nil
+	}
+	if h.MemoryMax != "" && !memoryMaxPattern.MatchString(h.MemoryMax) {
+		return fmt.Errorf("MemoryMax must look like 512M, 1G or 50%%")
+	}
+	if h.CPUQuota != "" && !cpuQuotaPattern.MatchString(h.CPUQuota) {
+		return fmt.Errorf("CPUQuota must be a percentage like 150%%")
+	}
+	switch h.ProtectSystem {
+	case "", "true", "full", "strict":
+	default:
+		return fmt.Errorf("ProtectSystem must be true, full or strict")
+	}
+	return nil
+}
+
+// ── Hardening ────────────────────────────────────────────────────────────────
+
+// handleServiceHardening returns the profile on GET. POST saves it and, when
+// a systemd unit is already installed, regenerates and restarts it.
+func handleServiceHardening(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResponse(w, map[string]interface{}{
+			"hardening": hardeningSettings(),
+			"defaults":  defaultHardening(),
+		})
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+
+	h := ServiceHardening{
+		Enabled:         r.FormValue("enabled") == "true",
+		MemoryMax:       strings.TrimSpace(r.FormValue("memory_max")),
+		CPUQuota:        strings.TrimSpace(r.FormValue("cpu_quota")),
+		NoNewPrivileges: r.FormValue("no_new_privileges") == "true",
+		ProtectSystem:   r.FormValue("protect_system"),
+		ProtectHome:     r.FormValue("protect_home") == "true",
+		PrivateTmp:      r.FormValue("private_tmp") == "true",
+	}
+	if err := h.validate(); err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+
+	settings := readSettings()
+	settings.Hardening = &h
+	if err := writeSettings(settings); err != nil {
+		errorResponse(w, "Failed to save settings: "+err.Error())
+		return
+	}
+
+	mgr, ok := detectServiceManager().(systemdManager)
+	if _, err := os.Stat(mgr.unitPath()); !ok || err != nil {
+		okResponse(w, "Saved — applied when the service is installed", nil)
+		return
+	}
+	spec, err := defaultServiceSpec()
+	if err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+	if err := mgr.Install(spec); err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+	if err := mgr.Restart(); err != nil {
+		errorResponse(w, "Unit updated but restart failed: "+err.Error())
+		return
+	}
+	okResponse(w, "Saved and applied — agent restarted", nil)
+}
+
+// ── Hardening Helpers ────────────────────────────────────────────────────────
+
+func totalRAMBytes() int64 {
+	if runtime.GOOS == "darwin" {
+		out, err := runCommand("sysctl", "-n", "hw.memsize")
+		if err != nil {
+			return 0
+		}
+		n, _ := strconv.ParseInt(out, 10, 64)
+		return n
+	}
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, _ := strconv.ParseInt(fields[1], 10, 64)
+			return kb * 1024
+		}
+	}
+	return 0
+}
@@ -149,7 +149,8 @@ fi
 log ""
 log "🔨 Building claw-setup..."
 [ ! -f go.mod ] && /usr/local/go/bin/go mod init claw-setup >> "$LOG_FILE" 2>&1
-/usr/local/go/bin/go build -o claw-setup . >> "$LOG_FILE" 2>&1
+VERSION=$(git -C "$REPO_DIR" describe --tags --always 2>/dev/null || echo dev)
+/usr/local/go/bin/go build -ldflags "-X main.version=$VERSION" -o claw-setup . >> "$LOG_FILE" 2>&1
 log "✓ Build complete"
 
 # ── Start ─────────────────────────────────────────────────────────────────────
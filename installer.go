@@ -0,0 +1,536 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	picoclawReleases   = "https://github.com/sipeed/picoclaw/releases"
+	picoclawChecksums  = "checksums.txt"
+	picoclawInstallDir = "/usr/local/bin"
+	userPathMarker     = "# claw-setup-path"
+)
+
+var assetNamePattern = regexp.MustCompile(`^picoclaw_[A-Za-z]+_[A-Za-z0-9_]+\.tar\.gz$`)
+
+// Release archives are tens of MB; a Pi on Wi-Fi needs far longer than
+// httpClient's 10s.
+var downloadClient = &http.Client{Timeout: 10 * time.Minute}
+
+// InstallError says which stage of an install failed, so the UI can tell a
+// network problem from a bad archive from a permissions problem.
+type InstallError struct {
+	Stage string
+	Err   error
+}
+
+func (e *InstallError) Error() string {
+	return strings.ToUpper(e.Stage[:1]) + e.Stage[1:] + " failed: " + e.Err.Error()
+}
+
+func stageError(stage string, format string, args ...interface{}) *InstallError {
+	return &InstallError{Stage: stage, Err: fmt.Errorf(format, args...)}
+}
+
+// ── Installer ────────────────────────────────────────────────────────────────
+
+// installPicoclaw downloads the release archive for this machine from
+// baseURL (a releases/download/<tag> or releases/latest/download URL),
+// checks it against the release's checksums file, and installs the binary
+// where target says (see picoclawDest). Progress goes to j; cancelling ctx
+// aborts the download.
+func installPicoclaw(ctx context.Context, baseURL, target string, j *Job) (string, error) {
+	platform, candidates, err := picoclawAssets()
+	if err != nil {
+		return "", &InstallError{Stage: "detect", Err: err}
+	}
+
+	// The checksums file doubles as the release's asset list, so a missing
+	// build is reported before anything large is downloaded
+	j.SetStage("Checking release")
+	sums, err := fetchChecksums(ctx, baseURL+"/"+picoclawChecksums)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", &InstallError{Stage: "checksum", Err: err}
+	}
+	asset := ""
+	for _, c := range candidates {
+		if _, ok := sums[c]; ok {
+			asset = c
+			break
+		}
+	}
+	if asset == "" {
+		return "", &InstallError{Stage: "detect", Err: noAssetError(platform, candidates, sums)}
+	}
+	j.Logf("Release asset for %s: %s", platform, asset)
+
+	tmpDir, err := os.MkdirTemp("", "picoclaw-install-*")
+	if err != nil {
+		return "", &InstallError{Stage: "download", Err: err}
+	}
+	defer os.RemoveAll(tmpDir)
+
+	j.SetStage("Downloading")
+	archive := filepath.Join(tmpDir, asset)
+	sum, err := downloadFile(ctx, baseURL+"/"+asset, archive, j.SetProgress)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", &InstallError{Stage: "download", Err: err}
+	}
+
+	j.SetStage("Verifying checksum")
+	if want := sums[asset]; !strings.EqualFold(want, sum) {
+		return "", stageError("checksum", "SHA256 mismatch for %s: expected %s, got %s", asset, want, sum)
+	}
+	j.Logf("SHA256 %s matches", sum)
+
+	return installArchive(ctx, archive, target, j)
+}
+
+// installLocalArchive installs a release archive that is already on disk —
+// uploaded or found on a USB stick — after checking it is meant for this
+// machine and matches want, a SHA256 the user vouches for.
+func installLocalArchive(ctx context.Context, archive, name, want, target string, j *Job) (string, error) {
+	platform, candidates, err := picoclawAssets()
+	if err != nil {
+		return "", &InstallError{Stage: "detect", Err: err}
+	}
+	// Only release-named archives can be checked; a renamed one is taken
+	// on trust and the checksum still has to match
+	if assetNamePattern.MatchString(name) && !slices.Contains(candidates, name) {
+		return "", stageError("detect", "%s is not a build for %s (expected %s)", name, platform, strings.Join(candidates, " or "))
+	}
+
+	j.SetStage("Verifying checksum")
+	sum, err := sha256File(archive)
+	if err != nil {
+		return "", &InstallError{Stage: "checksum", Err: err}
+	}
+	if !strings.EqualFold(want, sum) {
+		return "", stageError("checksum", "SHA256 mismatch for %s: expected %s, got %s", name, want, sum)
+	}
+	j.Logf("SHA256 %s matches", sum)
+
+	return installArchive(ctx, archive, target, j)
+}
+
+// installArchive is the part of an install shared by the download and
+// offline paths: extract the verified archive and put the binary in place
+func installArchive(ctx context.Context, archive, target string, j *Job) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "picoclaw-extract-*")
+	if err != nil {
+		return "", &InstallError{Stage: "extract", Err: err}
+	}
+	defer os.RemoveAll(tmpDir)
+
+	j.SetStage("Extracting")
+	bin, err := extractBinary(archive, "picoclaw", tmpDir)
+	if err != nil {
+		return "", &InstallError{Stage: "extract", Err: err}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	dest := picoclawDest(target)
+	j.SetStage("Installing to " + dest)
+	if err := installBinary(bin, dest); err != nil {
+		return "", &InstallError{Stage: "install", Err: err}
+	}
+	if filepath.Dir(dest) == userBinDir() {
+		if err := addUserBinToPath(); err != nil {
+			j.Logf("Could not add %s to your shell profile: %v", userBinDir(), err)
+		} else {
+			j.Logf("Added %s to PATH in ~/.profile", userBinDir())
+		}
+	}
+	return dest, nil
+}
+
+// picoclawDest picks where the binary goes. target is "system"
+// (picoclawInstallDir, needs root or passwordless sudo), "user"
+// (~/.local/bin) or "" to decide here: replace the binary already on PATH
+// if we can write there, so an upgrade doesn't leave an older copy
+// shadowing the new one, otherwise system when sudo works and user when
+// it doesn't.
+func picoclawDest(target string) string {
+	switch target {
+	case "system":
+		return filepath.Join(picoclawInstallDir, "picoclaw")
+	case "user":
+		return filepath.Join(userBinDir(), "picoclaw")
+	}
+	if path, err := exec.LookPath("picoclaw"); err == nil {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		if dirWritable(filepath.Dir(path)) || sudoAvailable() {
+			return path
+		}
+	}
+	return filepath.Join(defaultInstallDir(), "picoclaw")
+}
+
+// defaultInstallDir is picoclawInstallDir when we can write to it, directly
+// or through sudo, and ~/.local/bin otherwise — a browser can't answer a
+// sudo password prompt.
+func defaultInstallDir() string {
+	if dirWritable(picoclawInstallDir) || sudoAvailable() {
+		return picoclawInstallDir
+	}
+	return userBinDir()
+}
+
+func userBinDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "bin")
+}
+
+// picoclawAssets names the release archives that would run here, best
+// first, along with a readable platform name. It goes by the wizard's own
+// GOOS/GOARCH: that matches the userland, which is what matters on a Pi
+// running 32-bit Raspberry Pi OS on a 64-bit kernel.
+func picoclawAssets() (string, []string, error) {
+	var osName string
+	switch runtime.GOOS {
+	case "linux":
+		osName = "Linux"
+	case "darwin":
+		osName = "Darwin"
+	default:
+		return "", nil, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	var arches []string
+	switch runtime.GOARCH {
+	case "amd64":
+		arches = []string{"x86_64", "amd64"}
+	case "arm64":
+		arches = []string{"arm64", "aarch64"}
+	case "arm":
+		// An ARMv7 build won't run on a Pi Zero or Pi 1
+		if armV6() {
+			platform += "v6"
+			arches = []string{"armv6"}
+		} else {
+			platform += "v7"
+			arches = []string{"armv7", "arm"}
+		}
+	case "riscv64":
+		arches = []string{"riscv64"}
+	default:
+		return "", nil, fmt.Errorf("unsupported architecture: %s", platform)
+	}
+	if runtime.GOOS == "darwin" {
+		arches = append(arches, "all")
+	}
+
+	var names []string
+	for _, a := range arches {
+		names = append(names, "picoclaw_"+osName+"_"+a+".tar.gz")
+	}
+	return platform, names, nil
+}
+
+// armV6 reads /proc/cpuinfo rather than "CPU architecture", which the
+// BCM2835 misreports as 7
+func armV6() bool {
+	data, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "model name") && strings.Contains(line, "(v6l)") {
+			return true
+		}
+	}
+	return false
+}
+
+// noAssetError says what we looked for and what the release does have for
+// this OS, so "no build for your board" isn't mistaken for a network error
+func noAssetError(platform string, candidates []string, sums map[string]string) error {
+	prefix := candidates[0][:strings.LastIndex(candidates[0], "_")+1]
+	var available []string
+	for name := range sums {
+		if strings.HasPrefix(name, prefix) {
+			available = append(available, name)
+		}
+	}
+	sort.Strings(available)
+	if len(available) == 0 {
+		return fmt.Errorf("this release has no %s builds at all", strings.TrimSuffix(strings.TrimPrefix(prefix, "picoclaw_"), "_"))
+	}
+	return fmt.Errorf("this release has no build for %s (looked for %s; it has %s)",
+		platform, strings.Join(candidates, ", "), strings.Join(available, ", "))
+}
+
+// ── Installer Helpers ────────────────────────────────────────────────────────
+
+// downloadFile saves url to dest and returns the SHA256 of what was written.
+// progress, if set, is called with bytes so far and Content-Length (0 if unknown).
+func downloadFile(ctx context.Context, url, dest string, progress func(done, total int64)) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not reach %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned HTTP %d", url, resp.StatusCode)
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	var w io.Writer = io.MultiWriter(f, h)
+	if progress != nil {
+		w = &progressWriter{w: w, total: resp.ContentLength, report: progress}
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return "", fmt.Errorf("download interrupted: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fetchChecksums parses a goreleaser-style checksums file:
+// "<sha256>  <filename>" per line.
+func fetchChecksums(ctx context.Context, url string) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch checksums: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned HTTP %d", url, resp.StatusCode)
+	}
+	return parseChecksums(resp.Body)
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func parseChecksums(r io.Reader) (map[string]string, error) {
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		// sha256sum marks binary-mode entries with a leading '*'
+		sums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(sums) == 0 {
+		return nil, fmt.Errorf("checksums file is empty")
+	}
+	return sums, nil
+}
+
+// extractBinary pulls the first regular file called name out of a tar.gz,
+// wherever it sits in the archive, into destDir.
+func extractBinary(archive, name, destDir string) (string, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return "", fmt.Errorf("not a gzip archive: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return "", fmt.Errorf("no %s binary in the archive", name)
+		}
+		if err != nil {
+			return "", fmt.Errorf("corrupt archive: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || filepath.Base(hdr.Name) != name {
+			continue
+		}
+
+		dest := filepath.Join(destDir, name+".bin")
+		out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return "", fmt.Errorf("corrupt archive: %v", err)
+		}
+		return dest, out.Close()
+	}
+}
+
+// sudoAvailable reports whether privileged commands can run without a
+// password prompt
+func sudoAvailable() bool {
+	if os.Geteuid() == 0 {
+		return true
+	}
+	return run("sudo", "-n", "true") == nil
+}
+
+func dirWritable(dir string) bool {
+	f, err := os.CreateTemp(dir, ".claw-setup-*")
+	if err != nil {
+		return false
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return true
+}
+
+// ensureUserBinOnPath puts ~/.local/bin at the front of our own PATH, so
+// LookPath finds a user install even when the wizard was started from a
+// shell that predates it
+func ensureUserBinOnPath() {
+	dir := userBinDir()
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+	path := os.Getenv("PATH")
+	for _, d := range filepath.SplitList(path) {
+		if d == dir {
+			return
+		}
+	}
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+}
+
+// addUserBinToPath makes ~/.local/bin part of PATH for this process and for
+// future login shells, via a marked block in ~/.profile
+func addUserBinToPath() error {
+	ensureUserBinOnPath()
+	home, _ := os.UserHomeDir()
+	profile := filepath.Join(home, ".profile")
+	data, err := os.ReadFile(profile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if strings.Contains(string(data), userPathMarker) {
+		return nil
+	}
+	f, err := os.OpenFile(profile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "\n%s\ncase \":$PATH:\" in *\":$HOME/.local/bin:\"*) ;; *) export PATH=\"$HOME/.local/bin:$PATH\" ;; esac\n", userPathMarker)
+	return err
+}
+
+type progressWriter struct {
+	w      io.Writer
+	done   int64
+	total  int64
+	report func(done, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	if p.total < 0 {
+		p.total = 0
+	}
+	p.report(p.done, p.total)
+	return n, err
+}
+
+// installBinary puts src at dest, through sudo when dest isn't writable.
+// Both paths write a new inode, so a running picoclaw is not disturbed.
+func installBinary(src, dest string) error {
+	if err := copyExecutable(src, dest); err == nil {
+		return nil
+	}
+	if err := runPrivileged("install", "-m", "0755", src, dest); err != nil {
+		return fmt.Errorf("could not write %s: %v", dest, err)
+	}
+	return nil
+}
+
+// copyExecutable writes a temp file beside dest and renames it into place
+func copyExecutable(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".picoclaw-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	in, err := os.Open(src)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	_, err = io.Copy(tmp, in)
+	in.Close()
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	jobLogLimit  = 200
+	jobRetention = 10 * time.Minute
+)
+
+// JobState is what the browser sees of a background job. Status is
+// running, done, failed or cancelled; Done/Total count bytes or steps.
+type JobState struct {
+	ID         string                 `json:"id"`
+	Kind       string                 `json:"kind"`
+	Status     string                 `json:"status"`
+	Stage      string                 `json:"stage"`
+	Done       int64                  `json:"done"`
+	Total      int64                  `json:"total"`
+	Message    string                 `json:"message"`
+	Log        []string               `json:"log"`
+	Result     map[string]interface{} `json:"result,omitempty"`
+	StartedAt  time.Time              `json:"started_at"`
+	FinishedAt time.Time              `json:"finished_at"`
+}
+
+// Job is one long-running operation — an install, an upgrade — running in
+// the background so the request that started it can return at once.
+type Job struct {
+	mu      sync.Mutex
+	state   JobState
+	version int
+	cancel  context.CancelFunc
+	// echo prints log lines as well, for jobs run from the command line
+	echo bool
+}
+
+// JobFunc does the work. It should return promptly once ctx is cancelled.
+type JobFunc func(ctx context.Context, j *Job) (map[string]interface{}, error)
+
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+var jobs = &jobRegistry{jobs: map[string]*Job{}}
+
+func (j *Job) update(fn func(s *JobState)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.state)
+	j.version++
+}
+
+func (j *Job) snapshot() (JobState, int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	snap := j.state
+	snap.Log = append([]string(nil), j.state.Log...)
+	return snap, j.version
+}
+
+// SetStage starts a new stage and resets the progress counters
+func (j *Job) SetStage(stage string) {
+	j.update(func(s *JobState) {
+		s.Stage, s.Done, s.Total = stage, 0, 0
+	})
+	j.Logf("%s...", stage)
+}
+
+// SetProgress records done out of total; total 0 means unknown
+func (j *Job) SetProgress(done, total int64) {
+	j.update(func(s *JobState) {
+		s.Done, s.Total = done, total
+	})
+}
+
+func (j *Job) Logf(format string, args ...interface{}) {
+	line := fmt.Sprintf(format, args...)
+	if j.echo {
+		fmt.Println(line)
+	}
+	j.update(func(s *JobState) {
+		s.Log = append(s.Log, line)
+		if len(s.Log) > jobLogLimit {
+			s.Log = s.Log[len(s.Log)-jobLogLimit:]
+		}
+	})
+}
+
+// start runs fn in the background. Only one job of each kind runs at a time.
+func (r *jobRegistry) start(kind string, fn JobFunc) (*Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, j := range r.jobs {
+		snap, _ := j.snapshot()
+		if snap.Status == "running" && snap.Kind == kind {
+			return nil, fmt.Errorf("Another %s job is already running", kind)
+		}
+		if snap.Status != "running" && time.Since(snap.FinishedAt) > jobRetention {
+			delete(r.jobs, id)
+		}
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &Job{
+		cancel: cancel,
+		state: JobState{
+			ID:        hex.EncodeToString(b),
+			Kind:      kind,
+			Status:    "running",
+			StartedAt: time.Now(),
+		},
+	}
+	r.jobs[j.state.ID] = j
+
+	go func() {
+		defer cancel()
+		result, err := fn(ctx, j)
+		j.update(func(s *JobState) {
+			s.FinishedAt = time.Now()
+			s.Result = result
+			switch {
+			case errors.Is(err, context.Canceled):
+				s.Status, s.Message = "cancelled", "Cancelled"
+			case err != nil:
+				s.Status, s.Message = "failed", err.Error()
+			default:
+				s.Status = "done"
+				if msg, ok := result["message"].(string); ok {
+					s.Message = msg
+				}
+			}
+		})
+	}()
+	return j, nil
+}
+
+func (r *jobRegistry) get(id string) *Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.jobs[id]
+}
+
+// ── Jobs ─────────────────────────────────────────────────────────────────────
+
+// handleJobEvents streams a job's state over SSE until it finishes
+func handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	j := jobs.get(r.URL.Query().Get("id"))
+	if j == nil {
+		http.Error(w, "unknown job", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	last := -1
+	ticker := time.NewTicker(300 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		snap, version := j.snapshot()
+		if version != last {
+			last = version
+			b, _ := json.Marshal(snap)
+			fmt.Fprintf(w, "data: %s\n\n", b)
+			flusher.Flush()
+		}
+		if snap.Status != "running" {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func handleJobStatus(w http.ResponseWriter, r *http.Request) {
+	j := jobs.get(r.URL.Query().Get("id"))
+	if j == nil {
+		errorResponse(w, "Unknown job")
+		return
+	}
+	snap, _ := j.snapshot()
+	jsonResponse(w, snap)
+}
+
+func handleJobCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	j := jobs.get(r.FormValue("id"))
+	if j == nil {
+		errorResponse(w, "Unknown job")
+		return
+	}
+	j.cancel()
+	okResponse(w, "Cancelling", nil)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLogLines = 200
+	maxLogLines     = 10000
+)
+
+var logLevelPattern = regexp.MustCompile(`(?i)\b(?:level=)?(debug|info|warn|warning|error|fatal|panic)\b`)
+
+// LogLine is one line of agent output as sent to the browser
+type LogLine struct {
+	Line  string `json:"line"`
+	Level string `json:"level"`
+}
+
+// ── Logs ─────────────────────────────────────────────────────────────────────
+
+// handleLogStream follows the agent's log — via the service manager, or a
+// specific file when ?path= is given — and pushes each line as an SSE event.
+// Level filtering, search and pause happen in the browser.
+func handleLogStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	lines := logLinesParam(r)
+
+	argv := detectServiceManager().FollowLogs(lines)
+	if path := r.URL.Query().Get("path"); path != "" {
+		clean, err := allowedLogPath(path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		argv = followFile(lines, clean)
+	}
+
+	cmd := exec.CommandContext(r.Context(), argv[0], argv[1:]...)
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		http.Error(w, "could not start "+argv[0]+": "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer cmd.Wait()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	// Comment lines keep idle connections from being dropped by proxies.
+	// The writer is only valid until this handler returns, so the ticker
+	// is stopped and waited for first.
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	defer wg.Wait()
+	defer close(done)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(15 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+				mu.Lock()
+				fmt.Fprint(w, ": keepalive\n\n")
+				flusher.Flush()
+				mu.Unlock()
+			}
+		}
+	}()
+
+	scanner := bufio.NewScanner(pipe)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		b, _ := json.Marshal(LogLine{Line: line, Level: logLevel(line)})
+		mu.Lock()
+		fmt.Fprintf(w, "data: %s\n\n", b)
+		flusher.Flush()
+		mu.Unlock()
+	}
+}
+
+// handleLogDownload returns the last N lines as a plain-text attachment
+func handleLogDownload(w http.ResponseWriter, r *http.Request) {
+	lines := logLinesParam(r)
+
+	var out string
+	var err error
+	if path := r.URL.Query().Get("path"); path != "" {
+		clean, perr := allowedLogPath(path)
+		if perr != nil {
+			http.Error(w, perr.Error(), http.StatusForbidden)
+			return
+		}
+		out, err = tailFile(clean, lines)
+	} else {
+		out, err = detectServiceManager().Logs(lines)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	name := fmt.Sprintf("picoclaw-%s.log", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	fmt.Fprintln(w, out)
+}
+
+// ── Log Helpers ──────────────────────────────────────────────────────────────
+
+func logLinesParam(r *http.Request) int {
+	n, err := strconv.Atoi(r.URL.Query().Get("lines"))
+	if err != nil || n <= 0 {
+		return defaultLogLines
+	}
+	if n > maxLogLines {
+		return maxLogLines
+	}
+	return n
+}
+
+// logLevel normalises the first level keyword in a line to
+// debug/info/warn/error, or "" when the line carries none.
+func logLevel(line string) string {
+	m := logLevelPattern.FindStringSubmatch(line)
+	if m == nil {
+		return ""
+	}
+	switch strings.ToLower(m[1]) {
+	case "warning", "warn":
+		return "warn"
+	case "fatal", "panic", "error":
+		return "error"
+	}
+	return strings.ToLower(m[1])
+}
+
+// allowedLogPath limits ?path= to the exact log files the service backends
+// write — the wizard has no login, and the agent's data directory holds
+// config.json with every key and password, so nothing else is readable.
+func allowedLogPath(path string) (string, error) {
+	clean, err := filepath.Abs(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	for _, p := range serviceLogPaths() {
+		if clean == p {
+			return clean, nil
+		}
+	}
+	return "", fmt.Errorf("only the service log files can be viewed")
+}
+
+// serviceLogPaths are the files the OpenRC, supervisord, runit and launchd
+// backends log to
+func serviceLogPaths() []string {
+	return []string{
+		openrcLogPath,
+		supervisordLogPath,
+		filepath.Join(runitLogDir, "current"),
+		filepath.Join(launchdLogDir(), "picoclaw.log"),
+		filepath.Join(launchdLogDir(), "picoclaw.err.log"),
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"os/exec"
 	"strings"
 	)
@@ -15,6 +16,24 @@ var templateFiles embed.FS
 var tmpl *template.Template
 
 func main() {
+	ensureUserBinOnPath()
+	if len(os.Args) > 1 && os.Args[1] == "uninstall" {
+		runUninstallCLI(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "watchdog" {
+		runWatchdogCLI(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "self-update" {
+		runSelfUpdateCLI(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		fmt.Println("claw-setup", version)
+		return
+	}
+
 	var err error
 	tmpl, err = template.ParseFS(templateFiles, "templates/*.html")
 	if err != nil {
@@ -24,17 +43,61 @@ func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", handleIndex)
 	mux.HandleFunc("/api/system-check", handleSystemCheck)
+	mux.HandleFunc("/api/runtime", handleRuntime)
 	mux.HandleFunc("/api/validate-llm", handleValidateLLM)
 	mux.HandleFunc("/api/validate-telegram", handleValidateTelegram)
 	mux.HandleFunc("/api/save-telegram-user", handleSaveTelegramUser)
 	mux.HandleFunc("/api/ping-telegram", handlePingTelegram)
+	mux.HandleFunc("/api/validate-discord", handleValidateDiscord)
+	mux.HandleFunc("/api/discord-guilds", handleDiscordGuilds)
+	mux.HandleFunc("/api/save-discord-users", handleSaveDiscordUsers)
+	mux.HandleFunc("/api/ping-discord", handlePingDiscord)
+	mux.HandleFunc("/api/slack-manifest", handleSlackManifest)
+	mux.HandleFunc("/api/validate-slack", handleValidateSlack)
+	mux.HandleFunc("/api/slack-directory", handleSlackDirectory)
+	mux.HandleFunc("/api/save-slack-access", handleSaveSlackAccess)
+	mux.HandleFunc("/api/ping-slack", handlePingSlack)
+	mux.HandleFunc("/api/whatsapp-pair/start", handleWhatsAppPairStart)
+	mux.HandleFunc("/api/whatsapp-pair/stop", handleWhatsAppPairStop)
+	mux.HandleFunc("/api/whatsapp-pair/events", handleWhatsAppPairEvents)
+	mux.HandleFunc("/api/whatsapp-status", handleWhatsAppStatus)
+	mux.HandleFunc("/api/save-whatsapp-numbers", handleSaveWhatsAppNumbers)
+	mux.HandleFunc("/api/validate-matrix", handleValidateMatrix)
+	mux.HandleFunc("/api/matrix-rooms", handleMatrixRooms)
+	mux.HandleFunc("/api/save-matrix-room", handleSaveMatrixRoom)
+	mux.HandleFunc("/api/ping-matrix", handlePingMatrix)
+	mux.HandleFunc("/api/validate-email", handleValidateEmail)
+	mux.HandleFunc("/api/save-email-senders", handleSaveEmailSenders)
+	mux.HandleFunc("/api/ping-email", handlePingEmail)
+	mux.HandleFunc("/api/channels", handleChannels)
+	mux.HandleFunc("/api/channels/toggle", handleToggleChannel)
+	mux.HandleFunc("/api/channels/remove", handleRemoveChannel)
 	mux.HandleFunc("/api/generate-soul", handleGenerateSoul)
 	mux.HandleFunc("/api/save-soul", handleSaveSoul)
 	mux.HandleFunc("/api/install-service", handleInstallService)
 	mux.HandleFunc("/api/health", handleHealth)
 	mux.HandleFunc("/api/install-picoclaw", handleInstallPicoclaw)
+	mux.HandleFunc("/api/offline-archives", handleOfflineArchives)
+	mux.HandleFunc("/api/releases", handleReleases)
+	mux.HandleFunc("/api/releases/install", handleInstallRelease)
+	mux.HandleFunc("/api/releases/rollback", handleRollback)
+	mux.HandleFunc("/api/jobs", handleJobStatus)
+	mux.HandleFunc("/api/jobs/events", handleJobEvents)
+	mux.HandleFunc("/api/jobs/cancel", handleJobCancel)
 	mux.HandleFunc("/api/models", handleGetModels)
 	mux.HandleFunc("/api/restart-service", handleRestartService)
+	mux.HandleFunc("/api/enable-linger", handleEnableLinger)
+	mux.HandleFunc("/api/service-hardening", handleServiceHardening)
+	mux.HandleFunc("/api/service-drift", handleServiceDrift)
+	mux.HandleFunc("/api/service-env", handleServiceEnv)
+	mux.HandleFunc("/api/watchdog", handleWatchdog)
+	mux.HandleFunc("/api/service-drift/repair", handleRepairService)
+	mux.HandleFunc("/api/uninstall", handleUninstall)
+	mux.HandleFunc("/api/deployment", handleDeployment)
+	mux.HandleFunc("/api/self-update", handleSelfUpdate)
+	mux.HandleFunc("/api/preflight", handlePreflight)
+	mux.HandleFunc("/api/logs/stream", handleLogStream)
+	mux.HandleFunc("/api/logs/download", handleLogDownload)
 	mux.HandleFunc("/api/local-ip", handleLocalIP)
 
 	ip := getLocalIP()
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var mxidPattern = regexp.MustCompile(`^@[a-z0-9._=\-/+]+:[A-Za-z0-9.\-]+(:\d+)?$`)
+
+// ── Matrix ───────────────────────────────────────────────────────────────────
+
+func handleValidateMatrix(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	homeserver := strings.TrimSpace(r.FormValue("homeserver"))
+	user := strings.TrimSpace(r.FormValue("user"))
+	password := r.FormValue("password")
+	token := strings.TrimSpace(r.FormValue("access_token"))
+
+	if homeserver == "" {
+		errorResponse(w, "homeserver is required")
+		return
+	}
+	if token == "" && (user == "" || password == "") {
+		errorResponse(w, "Provide either an access token or a username and password")
+		return
+	}
+
+	base, err := resolveHomeserver(homeserver)
+	if err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+
+	deviceID := ""
+	if token == "" {
+		token, deviceID, err = matrixLogin(base, user, password)
+		if err != nil {
+			errorResponse(w, "Login failed: "+err.Error())
+			return
+		}
+	}
+
+	userID, err := matrixWhoami(base, token)
+	if err != nil {
+		errorResponse(w, "Token check failed: "+err.Error())
+		return
+	}
+
+	rt := currentRuntime()
+	cfg := readConfig(rt)
+	if cfg.Channels == nil {
+		cfg.Channels = make(map[string]map[string]interface{})
+	}
+	mx := cfg.Channels["matrix"]
+	if mx == nil {
+		mx = make(map[string]interface{})
+	}
+	mx["enabled"] = true
+	mx["homeserver"] = base
+	mx["user_id"] = userID
+	mx["access_token"] = token
+	if deviceID != "" {
+		mx["device_id"] = deviceID
+	}
+	cfg.Channels["matrix"] = mx
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
+
+	okResponse(w, "Logged in as "+userID, map[string]interface{}{
+		"user_id":    userID,
+		"homeserver": base,
+	})
+}
+
+func handleMatrixRooms(w http.ResponseWriter, r *http.Request) {
+	base, token := matrixCredentials()
+	if token == "" {
+		errorResponse(w, "Matrix not configured yet")
+		return
+	}
+
+	rooms, err := listMatrixRooms(base, token)
+	if err != nil {
+		errorResponse(w, "Failed to list rooms: "+err.Error())
+		return
+	}
+	jsonResponse(w, map[string]interface{}{
+		"ok":    true,
+		"rooms": rooms,
+	})
+}
+
+// handleSaveMatrixRoom joins an existing room (by ID or alias) or creates a
+// new private one, inviting the allowed users, and saves the access list.
+func handleSaveMatrixRoom(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	room := strings.TrimSpace(r.FormValue("room"))
+	roomName := strings.TrimSpace(r.FormValue("room_name"))
+	users := splitList(r.FormValue("user_ids"))
+
+	if len(users) == 0 {
+		errorResponse(w, "user_ids is required")
+		return
+	}
+	for _, u := range users {
+		if !mxidPattern.MatchString(u) {
+			errorResponse(w, "Not a Matrix ID (expected @user:server): "+u)
+			return
+		}
+	}
+
+	base, token := matrixCredentials()
+	if token == "" {
+		errorResponse(w, "Matrix not configured yet")
+		return
+	}
+
+	var roomID string
+	var err error
+	if room == "" {
+		if roomName == "" {
+			roomName = "PicoClaw"
+		}
+		roomID, err = createMatrixRoom(base, token, roomName, users)
+	} else {
+		roomID, err = joinMatrixRoom(base, token, room)
+	}
+	if err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+
+	rt := currentRuntime()
+	cfg := readConfig(rt)
+	if cfg.Channels == nil {
+		cfg.Channels = make(map[string]map[string]interface{})
+	}
+	if cfg.Channels["matrix"] == nil {
+		cfg.Channels["matrix"] = make(map[string]interface{})
+	}
+	cfg.Channels["matrix"]["room_id"] = roomID
+	cfg.Channels["matrix"]["allowFrom"] = users
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
+	okResponse(w, "Room "+roomID+" saved", map[string]interface{}{
+		"room_id": roomID,
+	})
+}
+
+func handlePingMatrix(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := readConfig(currentRuntime())
+	roomID, _ := cfg.Channels["matrix"]["room_id"].(string)
+	base, token := matrixCredentials()
+	if token == "" || roomID == "" {
+		errorResponse(w, "Pick a room first")
+		return
+	}
+
+	ok, msg := sendMatrixPing(base, token, roomID)
+	jsonResponse(w, map[string]interface{}{
+		"ok":      ok,
+		"message": msg,
+	})
+}
+
+func matrixCredentials() (string, string) {
+	cfg := readConfig(currentRuntime())
+	base, _ := cfg.Channels["matrix"]["homeserver"].(string)
+	token, _ := cfg.Channels["matrix"]["access_token"].(string)
+	return base, token
+}
+
+// ── Matrix API ───────────────────────────────────────────────────────────────
+
+type MatrixRoom struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// resolveHomeserver accepts "matrix.org", "https://matrix.example.com" or a
+// local "http://localhost:8008" and returns the client API base URL, honouring
+// .well-known delegation when the server publishes it.
+func resolveHomeserver(hs string) (string, error) {
+	if !strings.Contains(hs, "://") {
+		hs = "https://" + hs
+	}
+	u, err := url.Parse(hs)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("Invalid homeserver URL: %s", hs)
+	}
+	base := strings.TrimRight(u.Scheme+"://"+u.Host+u.Path, "/")
+
+	resp, err := httpClient.Get(base + "/.well-known/matrix/client")
+	if err == nil {
+		defer resp.Body.Close()
+		var wk struct {
+			Homeserver struct {
+				BaseURL string `json:"base_url"`
+			} `json:"m.homeserver"`
+		}
+		if resp.StatusCode == 200 && json.NewDecoder(resp.Body).Decode(&wk) == nil && wk.Homeserver.BaseURL != "" {
+			base = strings.TrimRight(wk.Homeserver.BaseURL, "/")
+		}
+	}
+
+	var versions struct {
+		Versions []string `json:"versions"`
+	}
+	if err := matrixRequest("GET", base, "", "/_matrix/client/versions", nil, &versions); err != nil {
+		return "", fmt.Errorf("No Matrix homeserver at %s: %v", base, err)
+	}
+	return base, nil
+}
+
+func matrixRequest(method, base, token, path string, body interface{}, out interface{}) error {
+	var rd io.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		rd = strings.NewReader(string(b))
+	}
+	req, _ := http.NewRequest(method, base+path, rd)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Connection failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		b, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(b, &e) == nil && e.ErrCode != "" {
+			return fmt.Errorf("%s: %s", e.ErrCode, e.Error)
+		}
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, truncate(string(b), 120))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+func matrixLogin(base, user, password string) (string, string, error) {
+	body := map[string]interface{}{
+		"type": "m.login.password",
+		"identifier": map[string]string{
+			"type": "m.id.user",
+			"user": user,
+		},
+		"password":                    password,
+		"initial_device_display_name": "PicoClaw",
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+		DeviceID    string `json:"device_id"`
+	}
+	err := matrixRequest("POST", base, "", "/_matrix/client/v3/login", body, &result)
+	return result.AccessToken, result.DeviceID, err
+}
+
+func matrixWhoami(base, token string) (string, error) {
+	var result struct {
+		UserID string `json:"user_id"`
+	}
+	err := matrixRequest("GET", base, token, "/_matrix/client/v3/account/whoami", nil, &result)
+	return result.UserID, err
+}
+
+func listMatrixRooms(base, token string) ([]MatrixRoom, error) {
+	var joined struct {
+		JoinedRooms []string `json:"joined_rooms"`
+	}
+	if err := matrixRequest("GET", base, token, "/_matrix/client/v3/joined_rooms", nil, &joined); err != nil {
+		return nil, err
+	}
+
+	rooms := []MatrixRoom{}
+	for _, id := range joined.JoinedRooms {
+		room := MatrixRoom{ID: id, Name: id}
+		var name struct {
+			Name string `json:"name"`
+		}
+		path := "/_matrix/client/v3/rooms/" + url.PathEscape(id) + "/state/m.room.name"
+		if matrixRequest("GET", base, token, path, nil, &name) == nil && name.Name != "" {
+			room.Name = name.Name
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, nil
+}
+
+func createMatrixRoom(base, token, name string, invite []string) (string, error) {
+	body := map[string]interface{}{
+		"name":      name,
+		"preset":    "trusted_private_chat",
+		"invite":    invite,
+		"is_direct": len(invite) == 1,
+	}
+	var result struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := matrixRequest("POST", base, token, "/_matrix/client/v3/createRoom", body, &result); err != nil {
+		return "", fmt.Errorf("Could not create room: %v", err)
+	}
+	return result.RoomID, nil
+}
+
+func joinMatrixRoom(base, token, room string) (string, error) {
+	var result struct {
+		RoomID string `json:"room_id"`
+	}
+	path := "/_matrix/client/v3/join/" + url.PathEscape(room)
+	if err := matrixRequest("POST", base, token, path, map[string]string{}, &result); err != nil {
+		return "", fmt.Errorf("Could not join %s: %v", room, err)
+	}
+	return result.RoomID, nil
+}
+
+func sendMatrixPing(base, token, roomID string) (bool, string) {
+	txnID := fmt.Sprintf("claw-setup-%d", time.Now().UnixNano())
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/send/m.room.message/" + txnID
+	body := map[string]string{
+		"msgtype": "m.text",
+		"body":    "🟢 Ping from claw-setup!\n\nYour PicoClaw agent is configured and ready.",
+	}
+	if err := matrixRequest("PUT", base, token, path, body, nil); err != nil {
+		return false, err.Error()
+	}
+	return true, "Message sent — check the room in your Matrix client"
+}
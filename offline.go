@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// mediaScanDepth is how far below a mount root archives are looked for —
+// /media/pi/USB/picoclaw/x.tar.gz is four levels down from /media
+const mediaScanDepth = 4
+
+var sha256Pattern = regexp.MustCompile(`(?i)\b[0-9a-f]{64}\b`)
+
+// MediaArchive is a picoclaw release archive found on removable media.
+// SHA256 comes from a checksums.txt or <archive>.sha256 beside it, if any —
+// from the same drive, so it only shows the copy isn't damaged. IntegrityOnly
+// marks that for API callers.
+type MediaArchive struct {
+	Path          string `json:"path"`
+	Name          string `json:"name"`
+	Size          int64  `json:"size"`
+	SHA256        string `json:"sha256"`
+	IntegrityOnly bool   `json:"integrity_only"`
+	Matches       bool   `json:"matches"`
+}
+
+// ── Offline Install ──────────────────────────────────────────────────────────
+
+func handleOfflineArchives(w http.ResponseWriter, r *http.Request) {
+	archives := findMediaArchives()
+	okResponse(w, "", map[string]interface{}{
+		"archives": archives,
+		"roots":    mediaRoots(),
+	})
+}
+
+// offlineInstall prepares an install from an uploaded archive
+// (source=upload) or one found by findMediaArchives (source=media). The
+// checksum comes from the sha256 field — a bare hash or a pasted
+// checksums.txt — or, for media, from a checksum file beside the archive.
+// Only a typed checksum counts as verified: whoever can swap the archive
+// can swap the file beside it too. cleanup removes the uploaded copy if the
+// install never runs.
+func offlineInstall(r *http.Request, source, target string) (install func(context.Context, *Job) (string, error), cleanup func(), verified bool, err error) {
+	var archive, name, sidecar, tmpDir string
+	switch source {
+	case "upload":
+		file, hdr, err := r.FormFile("archive")
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("Choose a release archive to upload")
+		}
+		defer file.Close()
+		name = filepath.Base(hdr.Filename)
+		if name == "." || name == ".." || name == string(filepath.Separator) {
+			return nil, nil, false, fmt.Errorf("Invalid archive file name: %q", hdr.Filename)
+		}
+		// The request body is gone once the handler returns, so the job
+		// works from a copy
+		tmpDir, err = os.MkdirTemp("", "picoclaw-upload-*")
+		if err != nil {
+			return nil, nil, false, err
+		}
+		archive = filepath.Join(tmpDir, name)
+		if err := saveUpload(file, archive); err != nil {
+			os.RemoveAll(tmpDir)
+			return nil, nil, false, fmt.Errorf("Upload failed: %v", err)
+		}
+	case "media":
+		path := r.FormValue("path")
+		// Only install what the scan found, not any path a request names
+		for _, a := range findMediaArchives() {
+			if a.Path == path {
+				archive, name, sidecar = a.Path, a.Name, a.SHA256
+			}
+		}
+		if archive == "" {
+			return nil, nil, false, fmt.Errorf("%s is not a picoclaw archive on removable media — scan again", path)
+		}
+	default:
+		return nil, nil, false, fmt.Errorf("Unknown install source: %s", source)
+	}
+
+	cleanup = func() {
+		if tmpDir != "" {
+			os.RemoveAll(tmpDir)
+		}
+	}
+	want := checksumFor(r.FormValue("sha256"), name)
+	verified = want != ""
+	if want == "" {
+		want = sidecar
+	}
+	if want == "" {
+		cleanup()
+		return nil, nil, false, fmt.Errorf("Enter the SHA256 of %s — it is listed in the release's %s", name, picoclawChecksums)
+	}
+
+	install = func(ctx context.Context, j *Job) (string, error) {
+		defer cleanup()
+		j.Logf("Installing from %s", archive)
+		if !verified {
+			j.Logf("Using the checksum found beside the archive — it only shows the copy isn't damaged, not that the release is genuine")
+		}
+		return installLocalArchive(ctx, archive, name, want, target, j)
+	}
+	return install, cleanup, verified, nil
+}
+
+// ── Offline Install Helpers ──────────────────────────────────────────────────
+
+// mediaRoots are where desktops and udisks mount USB drives and SD cards
+func mediaRoots() []string {
+	if runtime.GOOS == "darwin" {
+		return []string{"/Volumes"}
+	}
+	return []string{"/media", "/run/media", "/mnt"}
+}
+
+// findMediaArchives looks a few levels into each mounted volume for
+// picoclaw_*.tar.gz, best match for this machine first
+func findMediaArchives() []MediaArchive {
+	_, candidates, _ := picoclawAssets()
+	var found []MediaArchive
+	for _, root := range mediaRoots() {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				rel, _ := filepath.Rel(root, path)
+				if rel != "." && strings.Count(rel, string(filepath.Separator)) >= mediaScanDepth-1 {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			name := d.Name()
+			if !strings.HasPrefix(name, "picoclaw") || !strings.HasSuffix(name, ".tar.gz") {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			sum := sidecarChecksum(path)
+			found = append(found, MediaArchive{
+				Path:          path,
+				Name:          name,
+				Size:          info.Size(),
+				SHA256:        sum,
+				IntegrityOnly: sum != "",
+				Matches:       slices.Contains(candidates, name),
+			})
+			return nil
+		})
+	}
+	slices.SortStableFunc(found, func(a, b MediaArchive) int {
+		if a.Matches != b.Matches {
+			if a.Matches {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return found
+}
+
+// sidecarChecksum reads the release's checksums.txt copied beside the
+// archive, or an <archive>.sha256 file
+func sidecarChecksum(archive string) string {
+	name := filepath.Base(archive)
+	if data, err := os.ReadFile(filepath.Join(filepath.Dir(archive), picoclawChecksums)); err == nil {
+		if sum := checksumFor(string(data), name); sum != "" {
+			return sum
+		}
+	}
+	if data, err := os.ReadFile(archive + ".sha256"); err == nil {
+		return strings.ToLower(sha256Pattern.FindString(string(data)))
+	}
+	return ""
+}
+
+// checksumFor accepts a bare SHA256 or a whole checksums file, in which
+// case only the line for name counts
+func checksumFor(text, name string) string {
+	text = strings.TrimSpace(text)
+	if strings.Contains(text, "\n") || len(strings.Fields(text)) > 1 {
+		sums, err := parseChecksums(strings.NewReader(text))
+		if err != nil {
+			return ""
+		}
+		return strings.ToLower(sums[name])
+	}
+	if sha256Pattern.MatchString(text) && len(text) == 64 {
+		return strings.ToLower(text)
+	}
+	return ""
+}
+
+func saveUpload(src io.Reader, dest string) error {
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// clockWarnSkew and clockFailSkew bound how far the clock may be off.
+	// TLS only breaks once the clock leaves a certificate's validity, but
+	// signed webhooks and OAuth already fail at a few minutes.
+	clockWarnSkew = 5 * time.Minute
+	clockFailSkew = 24 * time.Hour
+	// clockFloor is earlier than any build of this wizard; a clock before
+	// it is a Pi without a real-time clock that hasn't synced yet
+	clockFloor = "2025-01-01"
+)
+
+// providerHosts are the LLM APIs the wizard validates keys against
+var providerHosts = map[string]string{
+	"openrouter": "https://openrouter.ai",
+	"anthropic":  "https://api.anthropic.com",
+	"gemini":     "https://generativelanguage.googleapis.com",
+	"groq":       "https://api.groq.com",
+}
+
+// preflightClient gives up quickly: a probe that needs longer than this
+// means a network that can't carry a download either
+var preflightClient = &http.Client{
+	Timeout: 5 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Requirements are what a runtime needs from the machine. Below a Warn
+// threshold the agent runs but is cramped; below a Min it won't install or
+// run reliably.
+type Requirements struct {
+	MinDisk, WarnDisk int64
+	MinRAM, WarnRAM   int64
+	// MinKernel is the oldest Linux kernel and MinMacOS the oldest macOS
+	// major version the agent's builds run on
+	MinKernel string
+	MinMacOS  int
+	// Arches are the platforms with official builds, as platformArch names them
+	Arches []string
+	// DownloadHost is where the install fetches from
+	DownloadHost string
+}
+
+// Requirement is one pre-flight check. Status is ok, warn or fail; a
+// failure blocks the install, unless Network is set and the install is
+// offline. Fix says what to do about anything but ok.
+type Requirement struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Detail  string `json:"detail"`
+	Fix     string `json:"fix,omitempty"`
+	Network bool   `json:"network"`
+}
+
+// ── Pre-flight ───────────────────────────────────────────────────────────────
+
+// handlePreflight runs every check. ready says an offline install may go
+// ahead, online_ready that a download may too.
+func handlePreflight(w http.ResponseWriter, r *http.Request) {
+	reqs := preflight(true)
+	ready, onlineReady := preflightReady(reqs)
+	okResponse(w, "", map[string]interface{}{
+		"requirements": reqs,
+		"ready":        ready,
+		"online_ready": onlineReady,
+	})
+}
+
+// preflight checks the machine against the current runtime's Requirements.
+// The network probes take up to a few seconds, so callers that only need
+// the local checks leave them out.
+func preflight(network bool) []Requirement {
+	rt := currentRuntime()
+	need := rt.Requirements()
+	reqs := []Requirement{
+		checkDisk(rt, need),
+		checkRAM(rt, need),
+		checkPlatform(rt, need),
+		checkHome(rt),
+	}
+	if !network {
+		return reqs
+	}
+
+	var wg sync.WaitGroup
+	probes := make([]Requirement, 3)
+	for i, check := range []func() Requirement{
+		func() Requirement { return checkDownloadHost(rt, need) },
+		checkProviders,
+		checkClock,
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probes[i] = check()
+		}()
+	}
+	wg.Wait()
+	return append(reqs, probes...)
+}
+
+// preflightReady reports whether nothing but network checks failed, and
+// whether nothing failed at all
+func preflightReady(reqs []Requirement) (bool, bool) {
+	ready, onlineReady := true, true
+	for _, req := range reqs {
+		if req.Status != "fail" {
+			continue
+		}
+		onlineReady = false
+		if !req.Network {
+			ready = false
+		}
+	}
+	return ready, onlineReady
+}
+
+// ── Local Checks ─────────────────────────────────────────────────────────────
+
+// checkDisk looks at the filesystems the binary and the data directory go
+// on, which are often the same SD card, and reports the tighter one
+func checkDisk(rt AgentRuntime, need Requirements) Requirement {
+	req := Requirement{ID: "disk", Name: "Free disk space"}
+	var path string
+	free := int64(-1)
+	for _, dir := range []string{existingParent(rt.DataDir()), existingParent(defaultInstallDir())} {
+		n, err := diskFree(dir)
+		if err == nil && (free < 0 || n < free) {
+			path, free = dir, n
+		}
+	}
+	if free < 0 {
+		return setStatus(req, "warn", "Could not read free disk space", "")
+	}
+
+	req.Detail = formatBytes(free) + " free on " + path
+	switch {
+	case free < need.MinDisk:
+		req.Status = "fail"
+		req.Fix = fmt.Sprintf("%s needs at least %s for itself and its workspace — free up %s on %s", rt.Name(), formatBytes(need.MinDisk), formatBytes(need.MinDisk-free), path)
+		if runtime.GOOS == "linux" {
+			req.Fix += ", e.g. with `sudo apt clean` and `sudo journalctl --vacuum-size=50M`"
+		}
+	case free < need.WarnDisk:
+		req.Status = "warn"
+		req.Fix = fmt.Sprintf("Under %s free — the workspace and logs grow over time, so keep an eye on it", formatBytes(need.WarnDisk))
+	default:
+		req.Status = "ok"
+	}
+	return req
+}
+
+func checkRAM(rt AgentRuntime, need Requirements) Requirement {
+	req := Requirement{ID: "ram", Name: "Memory"}
+	total, avail, err := memoryInfo()
+	if err != nil {
+		return setStatus(req, "warn", "Could not read memory size", "")
+	}
+
+	req.Detail = formatBytes(avail) + " free of " + formatBytes(total)
+	switch {
+	case total < need.MinRAM:
+		req.Status = "fail"
+		req.Fix = fmt.Sprintf("%s needs at least %s of RAM", rt.Name(), formatBytes(need.MinRAM))
+		if rt.ID() != "picoclaw" {
+			req.Fix += " — PicoClaw runs in far less, pick it above instead"
+		}
+	case total < need.WarnRAM:
+		req.Status = "warn"
+		req.Fix = fmt.Sprintf("%s is recommended — stop services you don't need, or add swap", formatBytes(need.WarnRAM))
+	case avail < need.MinRAM:
+		req.Status = "warn"
+		req.Fix = fmt.Sprintf("Under %s is free right now — stop services you don't need before starting the agent", formatBytes(need.MinRAM))
+	default:
+		req.Status = "ok"
+	}
+	return req
+}
+
+// checkPlatform covers the CPU and the OS version, since a build for the
+// right CPU still won't start on a kernel older than its toolchain supports
+func checkPlatform(rt AgentRuntime, need Requirements) Requirement {
+	req := Requirement{ID: "platform", Name: "OS and architecture"}
+	arch := platformArch()
+
+	osName, osVersion, fix := runtime.GOOS, "", ""
+	switch runtime.GOOS {
+	case "linux":
+		out, _ := runCommand("uname", "-r")
+		osName, osVersion = "Linux", strings.TrimSpace(out)
+		if osVersion != "" && !versionAtLeast(osVersion, need.MinKernel) {
+			fix = fmt.Sprintf("%s needs Linux %s or newer — update the OS (on a Pi, flash a current Raspberry Pi OS)", rt.Name(), need.MinKernel)
+		}
+	case "darwin":
+		out, _ := runCommand("sw_vers", "-productVersion")
+		osName, osVersion = "macOS", strings.TrimSpace(out)
+		if osVersion != "" && !versionAtLeast(osVersion, strconv.Itoa(need.MinMacOS)) {
+			fix = fmt.Sprintf("%s needs macOS %d or newer", rt.Name(), need.MinMacOS)
+		}
+	default:
+		fix = "Only Linux and macOS are supported"
+	}
+	req.Detail = strings.TrimSpace(osName+" "+osVersion) + " on " + arch
+
+	switch {
+	case fix != "":
+		return setStatus(req, "fail", req.Detail, fix)
+	case !slices.Contains(need.Arches, arch):
+		fix = fmt.Sprintf("%s has no official build for %s (it has %s)", rt.Name(), arch, strings.Join(need.Arches, ", "))
+		if rt.ID() != "picoclaw" {
+			fix += " — PicoClaw may, pick it above instead"
+		}
+		return setStatus(req, "fail", req.Detail, fix)
+	}
+	return setStatus(req, "ok", req.Detail, "")
+}
+
+// checkHome makes sure the config, workspace and the wizard's own files
+// can be written — a home owned by root after a `sudo` mishap is common
+func checkHome(rt AgentRuntime) Requirement {
+	req := Requirement{ID: "home", Name: "Writable home directory"}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return setStatus(req, "fail", "No home directory", "Set HOME to a directory you own and start the wizard again")
+	}
+	for _, dir := range []string{home, rt.DataDir(), wizardDir()} {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		if !dirWritable(dir) {
+			return setStatus(req, "fail", dir+" is not writable",
+				fmt.Sprintf("Give it back to your user: sudo chown -R %s %s", currentUsername(), shellQuote(dir)))
+		}
+	}
+	return setStatus(req, "ok", home, "")
+}
+
+// ── Network Checks ───────────────────────────────────────────────────────────
+
+func checkDownloadHost(rt AgentRuntime, need Requirements) Requirement {
+	req := Requirement{ID: "download", Name: "Download server", Network: true}
+	if err := probeHTTPS(need.DownloadHost); err != nil {
+		fix := "Check the network connection, DNS and any proxy or firewall — HTTPS (port 443) must be allowed out"
+		if rt.ID() == "picoclaw" {
+			fix += ", or install offline from a release archive below"
+		}
+		return setStatus(req, "fail", need.DownloadHost+": "+err.Error(), fix)
+	}
+	return setStatus(req, "ok", need.DownloadHost+" is reachable", "")
+}
+
+// checkProviders only warns: the agent needs just one of them, and it is
+// picked on the next step
+func checkProviders() Requirement {
+	req := Requirement{ID: "providers", Name: "LLM provider APIs", Network: true}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var down []string
+	for name, url := range providerHosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := probeHTTPS(url); err != nil {
+				mu.Lock()
+				down = append(down, name)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	slices.Sort(down)
+
+	switch {
+	case len(down) == len(providerHosts):
+		return setStatus(req, "warn", "None of the providers are reachable",
+			"The agent can't answer without one — check that HTTPS to the internet is allowed")
+	case len(down) > 0:
+		return setStatus(req, "warn", "Unreachable: "+strings.Join(down, ", "),
+			"Pick one of the reachable providers, or allow HTTPS to the others")
+	}
+	return setStatus(req, "ok", "All providers are reachable", "")
+}
+
+// checkClock compares the clock against a server's Date header over plain
+// HTTP, which still works when a wrong clock is what breaks TLS
+func checkClock() Requirement {
+	req := Requirement{ID: "clock", Name: "System time", Network: true}
+	now := time.Now()
+	fix := "Turn on time sync: sudo timedatectl set-ntp true"
+	if runtime.GOOS == "darwin" {
+		fix = "Turn on \"Set time and date automatically\" in System Settings › General › Date & Time"
+	}
+
+	floor, _ := time.Parse(time.DateOnly, clockFloor)
+	if now.Before(floor) {
+		return setStatus(req, "fail", "The clock says "+now.Format(time.DateOnly)+", so every HTTPS certificate looks invalid", fix)
+	}
+
+	resp, err := preflightClient.Head("http://github.com")
+	if err != nil {
+		return setStatus(req, "warn", "Could not compare with an internet time source", "")
+	}
+	resp.Body.Close()
+	remote, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return setStatus(req, "warn", "Could not compare with an internet time source", "")
+	}
+
+	skew := now.Sub(remote)
+	if skew < 0 {
+		skew = -skew
+	}
+	detail := "Off by " + skew.Round(time.Second).String()
+	switch {
+	case skew > clockFailSkew:
+		return setStatus(req, "fail", detail+" — TLS certificates and API signatures will be rejected", fix)
+	case skew > clockWarnSkew:
+		return setStatus(req, "warn", detail+" — signed requests such as Slack's may be rejected", fix)
+	}
+	return setStatus(req, "ok", "In sync", "")
+}
+
+// ── Pre-flight Helpers ───────────────────────────────────────────────────────
+
+func setStatus(req Requirement, status, detail, fix string) Requirement {
+	req.Status, req.Detail, req.Fix = status, detail, fix
+	return req
+}
+
+// probeHTTPS counts any HTTP response as reachable; a certificate that is
+// "not yet valid" or "expired" is almost always the local clock
+func probeHTTPS(url string) error {
+	resp, err := preflightClient.Head(url)
+	if err != nil {
+		if strings.Contains(err.Error(), "certificate has expired or is not yet valid") {
+			return fmt.Errorf("TLS certificate rejected — check the system time")
+		}
+		if strings.Contains(err.Error(), "no such host") {
+			return fmt.Errorf("DNS lookup failed")
+		}
+		if strings.Contains(err.Error(), "Client.Timeout") {
+			return fmt.Errorf("timed out")
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// platformArch names the CPU the way Requirements.Arches does, telling
+// the Pi Zero's armv6 from armv7
+func platformArch() string {
+	if runtime.GOARCH != "arm" {
+		return runtime.GOARCH
+	}
+	if armV6() {
+		return "armv6"
+	}
+	return "armv7"
+}
+
+func diskFree(path string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return int64(uint64(st.Bavail) * uint64(st.Bsize)), nil
+}
+
+// existingParent walks up to the nearest directory that exists, for space
+// checks on a directory the install has yet to create
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// versionAtLeast compares dotted numeric versions such as "6.1.21-v8+"
+// and "4.18"; anything after the numbers is ignored
+func versionAtLeast(have, want string) bool {
+	h, w := strings.Split(have, "."), strings.Split(want, ".")
+	for i := range w {
+		var a, b int
+		if i < len(h) {
+			a, _ = strconv.Atoi(leadingDigits(h[i]))
+		}
+		b, _ = strconv.Atoi(w[i])
+		if a != b {
+			return a > b
+		}
+	}
+	return true
+}
+
+func leadingDigits(s string) string {
+	for i, r := range s {
+		if r < '0' || r > '9' {
+			return s[:i]
+		}
+	}
+	return s
+}
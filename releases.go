@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	picoclawReleasesAPI = "https://api.github.com/repos/sipeed/picoclaw/releases"
+	releaseCacheTTL     = time.Hour
+	serviceSettleTime   = 15 * time.Second
+)
+
+var semverPattern = regexp.MustCompile(`v?(\d+)\.(\d+)\.(\d+)`)
+
+// Release is one picoclaw GitHub release as shown in the release browser
+type Release struct {
+	Tag        string    `json:"tag"`
+	Name       string    `json:"name"`
+	Notes      string    `json:"notes"`
+	Published  time.Time `json:"published"`
+	Prerelease bool      `json:"prerelease"`
+	URL        string    `json:"url"`
+}
+
+// releaseCache keeps the GitHub listing for an hour — the unauthenticated
+// API allows 60 requests an hour, and the system check runs often.
+var releaseCache struct {
+	mu       sync.Mutex
+	releases []Release
+	fetched  time.Time
+	fetching bool
+}
+
+func picoclawBackupDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".picoclaw", "backup")
+}
+
+// ── Releases ─────────────────────────────────────────────────────────────────
+
+func handleReleases(w http.ResponseWriter, r *http.Request) {
+	if currentRuntime().ID() != "picoclaw" {
+		errorResponse(w, "Releases are only tracked for PicoClaw")
+		return
+	}
+	releases, err := listReleases(r.URL.Query().Get("refresh") == "1")
+	if err != nil {
+		errorResponse(w, "Could not list releases: "+err.Error())
+		return
+	}
+	current := installedVersion()
+	latest, update := updateAvailable(current, releases)
+	prev, _ := os.ReadFile(filepath.Join(picoclawBackupDir(), "picoclaw.version"))
+	okResponse(w, "", map[string]interface{}{
+		"releases":         releases,
+		"current":          current,
+		"latest":           latest,
+		"update_available": update,
+		"pinned":           readSettings().PinnedVersion,
+		"rollback_to":      strings.TrimSpace(string(prev)),
+	})
+}
+
+// handleInstallRelease installs (and optionally pins) a tag as a background
+// upgrade job; pin=false with an empty tag just clears the pin.
+func handleInstallRelease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	if currentRuntime().ID() != "picoclaw" {
+		errorResponse(w, "Releases are only tracked for PicoClaw")
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	tag := strings.TrimSpace(r.FormValue("tag"))
+	pin := r.FormValue("pin") == "true"
+
+	// The tag ends up in download URLs and the pinned version, so only
+	// accept one GitHub actually lists
+	if tag != "" {
+		releases, err := listReleases(false)
+		if err != nil {
+			errorResponse(w, "Could not list releases: "+err.Error())
+			return
+		}
+		if !slices.ContainsFunc(releases, func(rel Release) bool { return rel.Tag == tag }) {
+			errorResponse(w, "Unknown release: "+tag)
+			return
+		}
+	}
+
+	settings := readSettings()
+	if pin {
+		settings.PinnedVersion = tag
+	} else {
+		settings.PinnedVersion = ""
+	}
+	if err := writeSettings(settings); err != nil {
+		errorResponse(w, "Failed to save settings: "+err.Error())
+		return
+	}
+	if tag == "" {
+		okResponse(w, "Version unpinned", nil)
+		return
+	}
+
+	job, err := jobs.start("upgrade", func(ctx context.Context, j *Job) (map[string]interface{}, error) {
+		return upgradePicoclaw(ctx, tag, j)
+	})
+	if err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+	okResponse(w, "Upgrade started", map[string]interface{}{"job_id": job.state.ID})
+}
+
+func handleRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	// The backup is a picoclaw binary; restoring it while another runtime
+	// is active would put the wrong agent behind the service
+	if currentRuntime().ID() != "picoclaw" {
+		errorResponse(w, "Releases are only tracked for PicoClaw")
+		return
+	}
+
+	job, err := jobs.start("upgrade", func(ctx context.Context, j *Job) (map[string]interface{}, error) {
+		version, err := rollbackPicoclaw(j)
+		if err != nil {
+			return nil, err
+		}
+		restartAfterChange(j)
+		return map[string]interface{}{"message": "Rolled back to " + version}, nil
+	})
+	if err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+	okResponse(w, "Rollback started", map[string]interface{}{"job_id": job.state.ID})
+}
+
+// upgradePicoclaw keeps the current binary, installs tag, and restarts the
+// service. If the service doesn't stay up, the old binary goes back.
+func upgradePicoclaw(ctx context.Context, tag string, j *Job) (map[string]interface{}, error) {
+	from := installedVersion()
+	hadBackup := false
+	if current, err := exec.LookPath("picoclaw"); err == nil {
+		j.SetStage("Backing up current binary")
+		if err := backupPicoclaw(current, from); err != nil {
+			return nil, fmt.Errorf("Could not back up the current binary: %v", err)
+		}
+		hadBackup = true
+	}
+
+	if _, err := installPicoclaw(ctx, releaseDownloadURL(tag), "", j); err != nil {
+		return nil, err
+	}
+
+	mgr := detectServiceManager()
+	if path, _ := mgr.Definition(ServiceSpec{}); !fileExists(path) {
+		return map[string]interface{}{"message": "Installed " + tag}, nil
+	}
+	j.SetStage("Restarting service")
+	if err := mgr.Restart(); err == nil && waitForService(mgr, j) {
+		return map[string]interface{}{"message": "Upgraded to " + tag + " — service is running"}, nil
+	}
+
+	if !hadBackup {
+		return nil, fmt.Errorf("%s installed but the service did not come up, and there is no previous version to roll back to", tag)
+	}
+	j.Logf("Service did not come up on %s — rolling back", tag)
+	if _, err := rollbackPicoclaw(j); err != nil {
+		return nil, fmt.Errorf("%s failed to start and rollback failed too: %v", tag, err)
+	}
+	restartAfterChange(j)
+	return nil, fmt.Errorf("%s failed to start — rolled back to %s", tag, from)
+}
+
+// ── Release Helpers ──────────────────────────────────────────────────────────
+
+func releaseDownloadURL(tag string) string {
+	if tag == "" || tag == "latest" {
+		return picoclawReleases + "/latest/download"
+	}
+	return picoclawReleases + "/download/" + tag
+}
+
+// defaultReleaseURL honours a pinned version for fresh installs
+func defaultReleaseURL() string {
+	return releaseDownloadURL(readSettings().PinnedVersion)
+}
+
+func listReleases(refresh bool) ([]Release, error) {
+	releaseCache.mu.Lock()
+	if !refresh && time.Since(releaseCache.fetched) < releaseCacheTTL {
+		defer releaseCache.mu.Unlock()
+		return releaseCache.releases, nil
+	}
+	releaseCache.mu.Unlock()
+
+	req, _ := http.NewRequest(http.MethodGet, picoclawReleasesAPI+"?per_page=30", nil)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub returned HTTP %d", resp.StatusCode)
+	}
+
+	var raw []struct {
+		TagName     string    `json:"tag_name"`
+		Name        string    `json:"name"`
+		Body        string    `json:"body"`
+		PublishedAt time.Time `json:"published_at"`
+		Prerelease  bool      `json:"prerelease"`
+		Draft       bool      `json:"draft"`
+		HTMLURL     string    `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	var releases []Release
+	for _, r := range raw {
+		if r.Draft {
+			continue
+		}
+		releases = append(releases, Release{
+			Tag: r.TagName, Name: r.Name, Notes: r.Body,
+			Published: r.PublishedAt, Prerelease: r.Prerelease, URL: r.HTMLURL,
+		})
+	}
+
+	releaseCache.mu.Lock()
+	releaseCache.releases, releaseCache.fetched = releases, time.Now()
+	releaseCache.mu.Unlock()
+	return releases, nil
+}
+
+// cachedReleases never blocks the system check on GitHub: it returns
+// what's cached and refreshes in the background when stale.
+func cachedReleases() []Release {
+	releaseCache.mu.Lock()
+	defer releaseCache.mu.Unlock()
+	if time.Since(releaseCache.fetched) > releaseCacheTTL && !releaseCache.fetching {
+		releaseCache.fetching = true
+		go func() {
+			listReleases(true)
+			releaseCache.mu.Lock()
+			releaseCache.fetching = false
+			releaseCache.mu.Unlock()
+		}()
+	}
+	return releaseCache.releases
+}
+
+// updateAvailable returns the newest stable tag and whether it is newer
+// than current. A pinned version never reports an update.
+func updateAvailable(current string, releases []Release) (string, bool) {
+	latest := ""
+	for _, r := range releases {
+		if !r.Prerelease {
+			latest = r.Tag
+			break
+		}
+	}
+	if latest == "" || current == "" || readSettings().PinnedVersion != "" {
+		return latest, false
+	}
+	return latest, compareVersions(latest, current) > 0
+}
+
+// installedVersion pulls the semver out of `picoclaw version`, "" if none
+func installedVersion() string {
+	out, err := runCommand("picoclaw", "version")
+	if err != nil {
+		return ""
+	}
+	if m := semverPattern.FindString(out); m != "" {
+		return "v" + strings.TrimPrefix(m, "v")
+	}
+	return ""
+}
+
+func compareVersions(a, b string) int {
+	ma, mb := semverPattern.FindStringSubmatch(a), semverPattern.FindStringSubmatch(b)
+	if ma == nil || mb == nil {
+		return strings.Compare(a, b)
+	}
+	for i := 1; i <= 3; i++ {
+		x, _ := strconv.Atoi(ma[i])
+		y, _ := strconv.Atoi(mb[i])
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+func backupPicoclaw(path, version string) error {
+	dir := picoclawBackupDir()
+	if err := copyExecutable(path, filepath.Join(dir, "picoclaw.prev")); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "picoclaw.version"), []byte(version+"\n"), 0644)
+}
+
+// rollbackPicoclaw puts the backed-up binary back where picoclaw lives now
+func rollbackPicoclaw(j *Job) (string, error) {
+	prev := filepath.Join(picoclawBackupDir(), "picoclaw.prev")
+	if !fileExists(prev) {
+		return "", fmt.Errorf("No previous version to roll back to")
+	}
+	version, _ := os.ReadFile(filepath.Join(picoclawBackupDir(), "picoclaw.version"))
+	// Same check as an install: the binary must run on this machine
+	if out, err := runCommand(prev, "version"); err != nil {
+		return "", fmt.Errorf("The backed-up binary does not run here: %s", out)
+	}
+	j.SetStage("Restoring previous binary")
+	if err := installBinary(prev, picoclawDest("")); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(version)), nil
+}
+
+func restartAfterChange(j *Job) {
+	mgr := detectServiceManager()
+	if path, _ := mgr.Definition(ServiceSpec{}); !fileExists(path) {
+		return
+	}
+	j.SetStage("Restarting service")
+	if err := mgr.Restart(); err != nil {
+		j.Logf("Restart failed: %v", err)
+		return
+	}
+	waitForService(mgr, j)
+}
+
+// waitForService gives the service serviceSettleTime before judging it: it
+// must still be active then, not just briefly before Restart=on-failure
+// kicks in.
+func waitForService(mgr ServiceManager, j *Job) bool {
+	j.Logf("Waiting %s for the service to settle", serviceSettleTime)
+	time.Sleep(serviceSettleTime)
+	if mgr.Status() != "active" {
+		j.Logf("Service is %s", mgr.Status())
+		return false
+	}
+	j.Logf("Service is running")
+	return true
+}
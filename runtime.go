@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// AgentRuntime is one agent the wizard can set up. Everything else works on
+// PicoConfig; a runtime whose config file looks different translates on
+// read and write.
+type AgentRuntime interface {
+	ID() string
+	Name() string
+	// Binary is the command name looked up on PATH
+	Binary() string
+	Version(path string) string
+	Install(ctx context.Context, target string, j *Job) (string, error)
+	Uninstall(path string) error
+	DataDir() string
+	ConfigPath() string
+	Workspace() string
+	ReadConfig() (PicoConfig, error)
+	WriteConfig(cfg PicoConfig) error
+	// ServiceArgs follow the binary in the service's command line
+	ServiceArgs() []string
+	WhatsAppLoginCommand() string
+	// ContainerImage is the default image for container deployments, and
+	// ContainerDataDir where DataDir is mounted inside it
+	ContainerImage() string
+	ContainerDataDir() string
+	Requirements() Requirements
+}
+
+func runtimes() []AgentRuntime {
+	return []AgentRuntime{picoclawRuntime{}, openclawRuntime{}}
+}
+
+// currentRuntime is the runtime picked at System Check, PicoClaw until then
+func currentRuntime() AgentRuntime {
+	id := readSettings().Runtime
+	for _, rt := range runtimes() {
+		if rt.ID() == id {
+			return rt
+		}
+	}
+	return picoclawRuntime{}
+}
+
+// ── Runtime ──────────────────────────────────────────────────────────────────
+
+// handleRuntime lists the runtimes on GET and switches on POST. The service
+// isn't touched: drift detection then offers to point it at the new agent.
+func handleRuntime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		var list []map[string]interface{}
+		for _, rt := range runtimes() {
+			path, _ := exec.LookPath(rt.Binary())
+			list = append(list, map[string]interface{}{
+				"id":        rt.ID(),
+				"name":      rt.Name(),
+				"installed": path != "",
+				"config":    rt.ConfigPath(),
+			})
+		}
+		okResponse(w, "", map[string]interface{}{"runtimes": list, "current": currentRuntime().ID()})
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	id := r.FormValue("runtime")
+
+	var picked AgentRuntime
+	for _, rt := range runtimes() {
+		if rt.ID() == id {
+			picked = rt
+		}
+	}
+	if picked == nil {
+		errorResponse(w, "Unknown runtime: "+id)
+		return
+	}
+	settings := readSettings()
+	settings.Runtime = picked.ID()
+	if err := writeSettings(settings); err != nil {
+		errorResponse(w, "Failed to save settings: "+err.Error())
+		return
+	}
+	okResponse(w, "Setting up "+picked.Name(), nil)
+}
+
+// ── PicoClaw ─────────────────────────────────────────────────────────────────
+
+type picoclawRuntime struct{}
+
+func (picoclawRuntime) ID() string     { return "picoclaw" }
+func (picoclawRuntime) Name() string   { return "PicoClaw" }
+func (picoclawRuntime) Binary() string { return "picoclaw" }
+
+func (picoclawRuntime) Version(path string) string {
+	out, _ := runCommand(path, "version")
+	return out
+}
+
+func (picoclawRuntime) Install(ctx context.Context, target string, j *Job) (string, error) {
+	return installPicoclaw(ctx, defaultReleaseURL(), target, j)
+}
+
+func (picoclawRuntime) Uninstall(path string) error {
+	return removeBinary(path)
+}
+
+func (picoclawRuntime) DataDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".picoclaw")
+}
+
+func (rt picoclawRuntime) ConfigPath() string {
+	return filepath.Join(rt.DataDir(), "config.json")
+}
+
+func (rt picoclawRuntime) Workspace() string {
+	return filepath.Join(rt.DataDir(), "workspace")
+}
+
+// PicoClaw's config.json is PicoConfig as-is
+func (rt picoclawRuntime) ReadConfig() (PicoConfig, error) {
+	var cfg PicoConfig
+	data, err := os.ReadFile(rt.ConfigPath())
+	if err != nil {
+		return cfg, nil
+	}
+	return cfg, json.Unmarshal(data, &cfg)
+}
+
+func (rt picoclawRuntime) WriteConfig(cfg PicoConfig) error {
+	path := rt.ConfigPath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	data, err := json.MarshalIndent(cfg, "", " ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (picoclawRuntime) ServiceArgs() []string        { return []string{"gateway"} }
+func (picoclawRuntime) WhatsAppLoginCommand() string { return defaultWhatsAppPairCmd }
+func (picoclawRuntime) ContainerImage() string       { return "ghcr.io/sipeed/picoclaw:latest" }
+func (picoclawRuntime) ContainerDataDir() string     { return "/root/.picoclaw" }
+
+// PicoClaw is a single Go binary that idles in a few MB; the kernel floor
+// is Go's
+func (picoclawRuntime) Requirements() Requirements {
+	return Requirements{
+		MinDisk: 50 << 20, WarnDisk: 200 << 20,
+		MinRAM: 64 << 20, WarnRAM: 128 << 20,
+		MinKernel:    "3.2",
+		MinMacOS:     11,
+		Arches:       []string{"amd64", "arm64", "armv7", "armv6", "riscv64"},
+		DownloadHost: "https://github.com",
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	openclawPackage  = "openclaw@latest"
+	openclawMinNode  = 22
+	openclawLoginCmd = "openclaw channels login"
+)
+
+// openclawKeys renames PicoConfig channel keys to their openclaw.json
+// names. Keys not listed are the same in both.
+var openclawKeys = map[string]map[string]string{
+	"telegram": {"token": "botToken"},
+	"slack":    {"bot_token": "botToken", "app_token": "appToken"},
+	"matrix":   {"user_id": "userId", "access_token": "accessToken", "device_id": "deviceId", "room_id": "roomId"},
+}
+
+// openclawManaged are the PicoConfig channel keys the wizard writes. When a
+// channel is saved these are replaced wholesale; any other key in
+// openclaw.json is left as it was.
+var openclawManaged = map[string][]string{
+	"telegram": {"enabled", "token", "allowFrom"},
+	"discord":  {"enabled", "token", "allowFrom"},
+	"slack":    {"enabled", "bot_token", "app_token", "allowFrom", "allowChannels"},
+	"matrix":   {"enabled", "homeserver", "user_id", "access_token", "device_id", "room_id", "allowFrom"},
+	"whatsapp": {"enabled", "bridge_url", "allowFrom"},
+	"email": {"enabled", "address", "username", "password", "imap_host", "imap_port", "imap_security",
+		"smtp_host", "smtp_port", "smtp_security", "folder", "allowFrom"},
+}
+
+type openclawRuntime struct{}
+
+func (openclawRuntime) ID() string     { return "openclaw" }
+func (openclawRuntime) Name() string   { return "OpenClaw" }
+func (openclawRuntime) Binary() string { return "openclaw" }
+
+func (openclawRuntime) Version(path string) string {
+	out, _ := runCommand(path, "--version")
+	return out
+}
+
+// Install uses npm — OpenClaw ships as a Node package, not a release
+// binary. A user install goes under ~/.local, so its bin lands in the same
+// ~/.local/bin a sudo-less PicoClaw install uses.
+func (openclawRuntime) Install(ctx context.Context, target string, j *Job) (string, error) {
+	j.SetStage("Checking Node.js")
+	out, err := runCommand("node", "--version")
+	if err != nil || !hasCommand("npm") {
+		return "", stageError("detect", "OpenClaw needs Node.js %d or newer with npm — install it from https://nodejs.org first", openclawMinNode)
+	}
+	major, _ := strconv.Atoi(strings.SplitN(strings.TrimPrefix(strings.TrimSpace(out), "v"), ".", 2)[0])
+	if major < openclawMinNode {
+		return "", stageError("detect", "OpenClaw needs Node.js %d or newer, this machine has %s", openclawMinNode, strings.TrimSpace(out))
+	}
+	j.Logf("Node.js %s", strings.TrimSpace(out))
+
+	if target == "" && defaultInstallDir() == userBinDir() {
+		target = "user"
+	}
+	args := []string{"install", "-g", openclawPackage}
+	name := "npm"
+	if target == "user" {
+		args = append(args, "--prefix", filepath.Dir(userBinDir()))
+	} else if os.Geteuid() != 0 {
+		name, args = "sudo", append([]string{"-n", "npm"}, args...)
+	}
+
+	j.SetStage("Installing " + openclawPackage + " with npm")
+	if err := runLogged(ctx, j, name, args...); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", &InstallError{Stage: "install", Err: err}
+	}
+	if target == "user" {
+		if err := addUserBinToPath(); err != nil {
+			j.Logf("Could not add %s to your shell profile: %v", userBinDir(), err)
+		}
+	}
+	path, err := exec.LookPath("openclaw")
+	if err != nil {
+		return "", stageError("install", "npm finished but openclaw is not on PATH")
+	}
+	return path, nil
+}
+
+func (openclawRuntime) Uninstall(path string) error {
+	if filepath.Dir(path) == userBinDir() {
+		return run("npm", "uninstall", "-g", "--prefix", filepath.Dir(userBinDir()), "openclaw")
+	}
+	return runPrivileged("npm", "uninstall", "-g", "openclaw")
+}
+
+func (openclawRuntime) DataDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".openclaw")
+}
+
+func (rt openclawRuntime) ConfigPath() string {
+	return filepath.Join(rt.DataDir(), "openclaw.json")
+}
+
+// Workspace honours agents.defaults.workspace, which may start with ~
+func (rt openclawRuntime) Workspace() string {
+	raw, _ := rt.readRaw()
+	agents, _ := raw["agents"].(map[string]interface{})
+	defaults, _ := agents["defaults"].(map[string]interface{})
+	if ws, _ := defaults["workspace"].(string); ws != "" {
+		if rest, ok := strings.CutPrefix(ws, "~/"); ok {
+			home, _ := os.UserHomeDir()
+			return filepath.Join(home, rest)
+		}
+		return ws
+	}
+	return filepath.Join(rt.DataDir(), "workspace")
+}
+
+// ReadConfig maps openclaw.json onto PicoConfig: provider keys live in its
+// env block as <PROVIDER>_API_KEY, and the model is "provider/model".
+func (rt openclawRuntime) ReadConfig() (PicoConfig, error) {
+	var cfg PicoConfig
+	raw, err := rt.readRaw()
+	if err != nil {
+		return cfg, err
+	}
+
+	if env, ok := raw["env"].(map[string]interface{}); ok {
+		for k, v := range env {
+			if key, ok := v.(string); ok && strings.HasSuffix(k, "_API_KEY") {
+				if cfg.Providers == nil {
+					cfg.Providers = map[string]map[string]interface{}{}
+				}
+				cfg.Providers[strings.ToLower(strings.TrimSuffix(k, "_API_KEY"))] = map[string]interface{}{"api_key": key}
+			}
+		}
+	}
+
+	agents, _ := raw["agents"].(map[string]interface{})
+	defaults, _ := agents["defaults"].(map[string]interface{})
+	model, _ := defaults["model"].(map[string]interface{})
+	if primary, ok := model["primary"].(string); ok && primary != "" {
+		d := map[string]interface{}{"model": primary}
+		if provider, rest, ok := strings.Cut(primary, "/"); ok {
+			if _, known := cfg.Providers[provider]; known {
+				d["provider"], d["model"] = provider, rest
+			}
+		}
+		cfg.Agents = map[string]interface{}{"defaults": d}
+	}
+
+	if channels, ok := raw["channels"].(map[string]interface{}); ok {
+		cfg.Channels = map[string]map[string]interface{}{}
+		for name, v := range channels {
+			ch, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			renamed := map[string]interface{}{}
+			for k, val := range ch {
+				renamed[openclawKey(name, k, true)] = val
+			}
+			cfg.Channels[name] = renamed
+		}
+	}
+	return cfg, nil
+}
+
+// WriteConfig merges cfg into the existing openclaw.json, so settings the
+// wizard doesn't know about survive. A channel or provider missing from cfg
+// was removed.
+func (rt openclawRuntime) WriteConfig(cfg PicoConfig) error {
+	raw, err := rt.readRaw()
+	if err != nil {
+		return err
+	}
+
+	env := childMap(raw, "env")
+	for k := range env {
+		if name, ok := strings.CutSuffix(k, "_API_KEY"); ok {
+			if _, keep := cfg.Providers[strings.ToLower(name)]; !keep {
+				delete(env, k)
+			}
+		}
+	}
+	for name, p := range cfg.Providers {
+		if key, ok := p["api_key"].(string); ok {
+			env[strings.ToUpper(name)+"_API_KEY"] = key
+		}
+	}
+	if len(env) == 0 {
+		delete(raw, "env")
+	}
+
+	prev, _ := rt.ReadConfig()
+	// Left alone when unchanged, so a model whose provider the wizard can't
+	// tell isn't rewritten by an unrelated save
+	if d, ok := cfg.Agents["defaults"].(map[string]interface{}); ok && !reflect.DeepEqual(d, prev.Agents["defaults"]) {
+		if model, _ := d["model"].(string); model != "" {
+			provider, _ := d["provider"].(string)
+			if provider == "" && len(cfg.Providers) == 1 {
+				for name := range cfg.Providers {
+					provider = name
+				}
+			}
+			if provider != "" && !strings.HasPrefix(model, provider+"/") {
+				model = provider + "/" + model
+			}
+			agents := childMap(raw, "agents")
+			childMap(childMap(agents, "defaults"), "model")["primary"] = model
+		}
+	}
+
+	channels := childMap(raw, "channels")
+	for name := range channels {
+		if _, ok := cfg.Channels[name]; !ok {
+			delete(channels, name)
+		}
+	}
+	for name, ch := range cfg.Channels {
+		out := map[string]interface{}{}
+		prev, _ := channels[name].(map[string]interface{})
+		for k, v := range prev {
+			if !slices.Contains(openclawManaged[name], openclawKey(name, k, true)) {
+				out[k] = v
+			}
+		}
+		for k, v := range ch {
+			out[openclawKey(name, k, false)] = v
+		}
+		channels[name] = out
+	}
+
+	path := rt.ConfigPath()
+	os.MkdirAll(filepath.Dir(path), 0700)
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Provider keys sit in this file, hence 0600
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+func (openclawRuntime) ServiceArgs() []string        { return []string{"gateway"} }
+func (openclawRuntime) WhatsAppLoginCommand() string { return openclawLoginCmd }
+func (openclawRuntime) ContainerImage() string       { return "ghcr.io/openclaw/openclaw:latest" }
+
+// The image runs as the unprivileged node user
+func (openclawRuntime) ContainerDataDir() string { return "/home/node/.openclaw" }
+
+// OpenClaw's floor is Node 22's: no armv6 or riscv64 builds, kernel 4.18.
+// MinRAM sits below 1GB so a 1GB Pi, which reports less, still passes.
+func (openclawRuntime) Requirements() Requirements {
+	return Requirements{
+		MinDisk: 500 << 20, WarnDisk: 1 << 30,
+		MinRAM: 768 << 20, WarnRAM: 2 << 30,
+		MinKernel:    "4.18",
+		MinMacOS:     11,
+		Arches:       []string{"amd64", "arm64", "armv7"},
+		DownloadHost: "https://registry.npmjs.org",
+	}
+}
+
+// ── OpenClaw Helpers ─────────────────────────────────────────────────────────
+
+// readRaw loads openclaw.json as a generic map. OpenClaw also accepts
+// JSON5; a file with comments is reported rather than rewritten without them.
+func (rt openclawRuntime) readRaw() (map[string]interface{}, error) {
+	raw := map[string]interface{}{}
+	data, err := os.ReadFile(rt.ConfigPath())
+	if os.IsNotExist(err) {
+		return raw, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s is not plain JSON (comments or trailing commas?) — the wizard won't overwrite it: %v", rt.ConfigPath(), err)
+	}
+	return raw, nil
+}
+
+// openclawKey translates a channel key to openclaw.json's name, or back
+// when reverse is set
+func openclawKey(channel, key string, reverse bool) string {
+	for pico, oc := range openclawKeys[channel] {
+		if !reverse && key == pico {
+			return oc
+		}
+		if reverse && key == oc {
+			return pico
+		}
+	}
+	return key
+}
+
+// childMap returns m[key] as a map, creating it if it is missing
+func childMap(m map[string]interface{}, key string) map[string]interface{} {
+	child, ok := m[key].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+		m[key] = child
+	}
+	return child
+}
+
+// runLogged runs a command and copies its output into the job log line by
+// line, so a slow npm install visibly makes progress
+func runLogged(ctx context.Context, j *Job, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(out)
+	var last string
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			j.Logf("%s", line)
+			last = line
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		if last != "" {
+			return fmt.Errorf("%s %s: %v (%s)", name, strings.Join(args, " "), err, last)
+		}
+		return fmt.Errorf("%s %s: %v", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
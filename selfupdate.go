@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	wizardReleasesAPI = "https://api.github.com/repos/arpit0515/claw-setup-wizard/releases/latest"
+	wizardChecksums   = "checksums.txt"
+	// wizardSignature is an ed25519 signature over checksums.txt
+	wizardSignature = "checksums.txt.sig"
+	// updatePublicKey is the base64 ed25519 key the project signs release
+	// checksums with. A release without a valid signature is refused, and
+	// while this is empty self-update is switched off altogether.
+	updatePublicKey = ""
+)
+
+// version is the wizard's own release tag, stamped at build time with
+// -ldflags "-X main.version=v1.2.3". Source builds stay "dev".
+var version = "dev"
+
+// WizardRelease is the newest claw-setup release and the asset for this
+// platform, if it ships one
+type WizardRelease struct {
+	Tag      string `json:"tag"`
+	URL      string `json:"url"`
+	Asset    string `json:"asset"`
+	assetURL string
+	sumsURL  string
+	sigURL   string
+}
+
+var wizardReleaseCache struct {
+	mu      sync.Mutex
+	release *WizardRelease
+	fetched time.Time
+}
+
+// ── Self-Update ──────────────────────────────────────────────────────────────
+
+// handleSelfUpdate reports the running and latest version on GET; POST
+// replaces the binary as a job, then restarts the wizard once the job's
+// result has had time to reach the browser. A build without an update key
+// answers neither, so the UI never offers an update it can't verify.
+func handleSelfUpdate(w http.ResponseWriter, r *http.Request) {
+	if _, err := updateKey(); err != nil {
+		jsonResponse(w, map[string]interface{}{
+			"ok":      false,
+			"message": err.Error(),
+			"current": version,
+		})
+		return
+	}
+	if r.Method != http.MethodPost {
+		rel, err := latestWizardRelease(r.URL.Query().Get("refresh") == "1")
+		if err != nil {
+			// current still tells a restarting browser which binary answered
+			jsonResponse(w, map[string]interface{}{
+				"ok":      false,
+				"message": "Could not check for updates: " + err.Error(),
+				"current": version,
+			})
+			return
+		}
+		okResponse(w, "", map[string]interface{}{
+			"current":          version,
+			"latest":           rel.Tag,
+			"url":              rel.URL,
+			"update_available": rel.Asset != "" && wizardUpdateAvailable(rel.Tag),
+		})
+		return
+	}
+
+	rel, err := latestWizardRelease(true)
+	if err != nil {
+		errorResponse(w, "Could not check for updates: "+err.Error())
+		return
+	}
+	if !wizardUpdateAvailable(rel.Tag) {
+		errorResponse(w, "claw-setup "+version+" is already the latest version")
+		return
+	}
+	if err := rel.installable(); err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+	job, err := jobs.start("self-update", func(ctx context.Context, j *Job) (map[string]interface{}, error) {
+		if err := selfUpdate(ctx, rel, j); err != nil {
+			return nil, err
+		}
+		go func() {
+			time.Sleep(2 * time.Second)
+			if err := reexec(); err != nil {
+				fmt.Println("❌ Could not restart:", err)
+			}
+		}()
+		return map[string]interface{}{"message": "Updated to " + rel.Tag + " — restarting", "version": rel.Tag}, nil
+	})
+	if err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+	okResponse(w, "Update started", map[string]interface{}{"job_id": job.state.ID})
+}
+
+// runSelfUpdateCLI handles `claw-setup self-update [flags]`
+func runSelfUpdateCLI(args []string) {
+	fs := flag.NewFlagSet("self-update", flag.ExitOnError)
+	check := fs.Bool("check", false, "only report whether an update is available")
+	force := fs.Bool("force", false, "reinstall even if this is the latest version")
+	fs.Parse(args)
+
+	if _, err := updateKey(); err != nil {
+		fmt.Println("❌", err)
+		os.Exit(1)
+	}
+	rel, err := latestWizardRelease(true)
+	if err != nil {
+		fmt.Println("❌ Could not check for updates:", err)
+		os.Exit(1)
+	}
+	if !wizardUpdateAvailable(rel.Tag) && !*force {
+		fmt.Println("claw-setup", version, "is up to date")
+		return
+	}
+	if err := rel.installable(); err != nil {
+		fmt.Println("❌", err)
+		os.Exit(1)
+	}
+	if *check {
+		fmt.Printf("claw-setup %s is available (running %s)\n", rel.Tag, version)
+		return
+	}
+
+	if err := selfUpdate(context.Background(), rel, &Job{echo: true}); err != nil {
+		fmt.Println("❌", err)
+		os.Exit(1)
+	}
+	fmt.Println("✓ Updated to", rel.Tag)
+	// Nothing to restart when run from a terminal; a wizard that is already
+	// running keeps the old binary until it is restarted
+	fmt.Println("Restart claw-setup to run the new version")
+}
+
+// selfUpdate downloads this platform's binary from rel, checks it against
+// the release's signed checksums, makes sure it runs, and renames it over
+// the running executable. Callers check rel.installable first.
+func selfUpdate(ctx context.Context, rel *WizardRelease, j *Job) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+
+	j.SetStage("Verifying checksums")
+	want, err := verifiedChecksum(ctx, rel)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "claw-setup-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmp := filepath.Join(tmpDir, rel.Asset)
+
+	j.SetStage("Downloading " + rel.Asset)
+	j.Logf("Downloading %s", rel.assetURL)
+	got, err := downloadFile(ctx, rel.assetURL, tmp, j.SetProgress)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("Checksum mismatch for %s: expected %s, got %s", rel.Asset, want, got)
+	}
+	j.Logf("SHA256 %s matches", got)
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return err
+	}
+	// A binary for the wrong CPU would pass the checksum and then never start
+	out, err := runCommand(tmp, "version")
+	if err != nil {
+		return fmt.Errorf("The downloaded binary does not run here: %v", err)
+	}
+	j.Logf("New binary reports %s", out)
+
+	j.SetStage("Replacing " + exe)
+	if err := installBinary(tmp, exe); err != nil {
+		return err
+	}
+	j.Logf("Installed %s", exe)
+	return nil
+}
+
+// ── Self-Update Helpers ──────────────────────────────────────────────────────
+
+// latestWizardRelease asks GitHub for the latest release, cached for an
+// hour like the picoclaw release list
+func latestWizardRelease(refresh bool) (*WizardRelease, error) {
+	wizardReleaseCache.mu.Lock()
+	defer wizardReleaseCache.mu.Unlock()
+	if !refresh && wizardReleaseCache.release != nil && time.Since(wizardReleaseCache.fetched) < releaseCacheTTL {
+		return wizardReleaseCache.release, nil
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, wizardReleasesAPI, nil)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("no releases published yet")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub returned HTTP %d", resp.StatusCode)
+	}
+	var raw struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+		Assets  []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	rel := &WizardRelease{Tag: raw.TagName, URL: raw.HTMLURL}
+	want := wizardAssetName()
+	for _, a := range raw.Assets {
+		switch a.Name {
+		case want:
+			rel.Asset, rel.assetURL = a.Name, a.URL
+		case wizardChecksums:
+			rel.sumsURL = a.URL
+		case wizardSignature:
+			rel.sigURL = a.URL
+		}
+	}
+	wizardReleaseCache.release, wizardReleaseCache.fetched = rel, time.Now()
+	return rel, nil
+}
+
+// wizardAssetName is the release binary for this platform, e.g.
+// claw-setup-linux-arm64. The arm build is GOARM=6, which armv7 runs too.
+func wizardAssetName() string {
+	return "claw-setup-" + runtime.GOOS + "-" + runtime.GOARCH
+}
+
+// installable says why rel can't replace this binary, if it can't
+func (rel *WizardRelease) installable() error {
+	if rel.Asset == "" {
+		return fmt.Errorf("%s has no build for %s/%s — update from source with install.sh", rel.Tag, runtime.GOOS, runtime.GOARCH)
+	}
+	return nil
+}
+
+// updateKey decodes updatePublicKey; without one self-update is off
+func updateKey() (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(updatePublicKey)
+	if updatePublicKey == "" || err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("Self-update is off in this build: it has no release signing key to verify updates with — update with install.sh")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// wizardUpdateAvailable treats a source build ("dev" or a commit hash) as
+// older than any release, so it can move onto prebuilt binaries
+func wizardUpdateAvailable(latest string) bool {
+	if latest == "" {
+		return false
+	}
+	if semverPattern.FindString(version) == "" {
+		return true
+	}
+	return compareVersions(latest, version) > 0
+}
+
+// verifiedChecksum returns the asset's SHA256 from checksums.txt, once the
+// file's signature has verified against updatePublicKey
+func verifiedChecksum(ctx context.Context, rel *WizardRelease) (string, error) {
+	key, err := updateKey()
+	if err != nil {
+		return "", err
+	}
+	if rel.sumsURL == "" {
+		return "", fmt.Errorf("%s has no %s — refusing to install an unverified binary", rel.Tag, wizardChecksums)
+	}
+	if rel.sigURL == "" {
+		return "", fmt.Errorf("%s is not signed — refusing to update", rel.Tag)
+	}
+	sums, err := fetchBytes(ctx, rel.sumsURL)
+	if err != nil {
+		return "", err
+	}
+	sig, err := fetchBytes(ctx, rel.sigURL)
+	if err != nil {
+		return "", err
+	}
+	// Accept the raw 64 bytes or their base64 encoding
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err == nil {
+		sig = decoded
+	}
+	if !ed25519.Verify(key, sums, sig) {
+		return "", fmt.Errorf("The signature on %s's checksums does not verify — refusing to update", rel.Tag)
+	}
+	want := checksumFor(string(sums), rel.Asset)
+	if want == "" {
+		return "", fmt.Errorf("%s does not list %s", wizardChecksums, rel.Asset)
+	}
+	return want, nil
+}
+
+func fetchBytes(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not reach %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned HTTP %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// reexec replaces this process with the binary now at its path. The
+// listening socket is close-on-exec, so the new process can bind it again.
+func reexec() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
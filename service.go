@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const serviceName = "picoclaw"
+
+// ServiceSpec describes the agent process a service manager should supervise.
+// DataDir is the agent's config and default workspace, and Workspace the
+// configured one; both are kept writable under ProtectHome.
+type ServiceSpec struct {
+	Description string
+	ExecPath    string
+	Args        []string
+	User        string
+	Home        string
+	WorkDir     string
+	DataDir     string
+	Workspace   string
+	Env         map[string]string
+	EnvFile     string
+	Hardening   ServiceHardening
+}
+
+// ServiceManager is one init system / process supervisor the agent can run
+// under. Status returns "active" or "inactive", like getServiceStatus always has.
+// FollowLogs returns the argv of a command that prints the last lines of the
+// agent's log and then keeps streaming new ones. Definition returns the file
+// Install writes for spec and what it would contain.
+type ServiceManager interface {
+	Name() string
+	Definition(spec ServiceSpec) (path, content string)
+	Install(spec ServiceSpec) error
+	Uninstall() error
+	Start() error
+	Stop() error
+	Restart() error
+	Status() string
+	Logs(lines int) (string, error)
+	FollowLogs(lines int) []string
+}
+
+// detectServiceManager picks the backend for this machine: a container
+// deployment if one was chosen, launchd on macOS, otherwise whichever init
+// system or supervisor is actually running.
+func detectServiceManager() ServiceManager {
+	if c := readSettings().Container; c != nil {
+		return containerManager{*c}
+	}
+	if runtime.GOOS == "darwin" {
+		return launchdManager{}
+	}
+	if _, err := os.Stat("/run/systemd/system"); err == nil && hasCommand("systemctl") {
+		// A system unit installed as the fallback wins over the user manager
+		if _, err := os.Stat(systemdManager{}.unitPath()); err == nil {
+			return systemdManager{}
+		}
+		// A user manager needs a logind session; without one fall back to a system unit
+		if _, err := runCommand("systemctl", "--user", "show-environment"); err == nil {
+			return systemdManager{user: true}
+		}
+		return systemdManager{}
+	}
+	if _, err := os.Stat("/run/openrc"); err == nil && hasCommand("rc-service") {
+		return openrcManager{}
+	}
+	if hasCommand("sv") && runitServiceDir() != "" {
+		return runitManager{}
+	}
+	if hasCommand("supervisorctl") {
+		return supervisordManager{}
+	}
+	return systemdManager{user: true}
+}
+
+// defaultServiceSpec runs the current runtime's gateway (`picoclaw gateway`
+// by default) as the current user from $HOME. The service keeps the
+// picoclaw name whichever runtime it runs.
+func defaultServiceSpec() (ServiceSpec, error) {
+	rt := currentRuntime()
+	home, _ := os.UserHomeDir()
+	// A container brings its own binary
+	if readSettings().Container != nil {
+		return ServiceSpec{
+			Description: rt.Name() + " AI Agent",
+			Args:        rt.ServiceArgs(),
+			User:        currentUsername(),
+			Home:        home,
+			WorkDir:     home,
+			DataDir:     rt.DataDir(),
+			Workspace:   rt.Workspace(),
+			EnvFile:     envFilePath(),
+		}, nil
+	}
+	agentPath, err := exec.LookPath(rt.Binary())
+	if err != nil {
+		return ServiceSpec{}, fmt.Errorf("%s not found in PATH", rt.Binary())
+	}
+	env := map[string]string{"HOME": home}
+	// Service managers start with a bare system PATH; a binary in
+	// ~/.local/bin (or any other odd place) needs its directory added so
+	// tools installed beside it are found too
+	if dir := filepath.Dir(agentPath); !systemBinDir(dir) {
+		env["PATH"] = dir + ":" + defaultServicePath
+	}
+	return ServiceSpec{
+		Description: rt.Name() + " AI Agent",
+		ExecPath:    agentPath,
+		Args:        rt.ServiceArgs(),
+		User:        currentUsername(),
+		Home:        home,
+		WorkDir:     home,
+		DataDir:     rt.DataDir(),
+		Workspace:   rt.Workspace(),
+		Env:         env,
+		EnvFile:     envFilePath(),
+		Hardening:   hardeningSettings(),
+	}, nil
+}
+
+const defaultServicePath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+func systemBinDir(dir string) bool {
+	for _, d := range filepath.SplitList(defaultServicePath) {
+		if d == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// envWithFile merges the user's environment file into Env, for backends
+// that can't load the file themselves
+func (s ServiceSpec) envWithFile() map[string]string {
+	env := map[string]string{}
+	if s.EnvFile != "" {
+		for k, v := range readEnvFile(s.EnvFile) {
+			env[k] = v
+		}
+	}
+	for k, v := range s.Env {
+		env[k] = v
+	}
+	return env
+}
+
+// envExports renders envWithFile as sh export lines. The environment file
+// is user-writable and init scripts run as root, so it is only ever parsed
+// as KEY=value data, never sourced.
+func (s ServiceSpec) envExports() string {
+	env := s.envWithFile()
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		if envKeyPattern.MatchString(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "export %s=%s\n", k, shellQuote(env[k]))
+	}
+	return b.String()
+}
+
+func (s ServiceSpec) command() string {
+	return strings.TrimSpace(shellQuote(s.ExecPath) + " " + strings.Join(s.Args, " "))
+}
+
+// ── Service Helpers ──────────────────────────────────────────────────────────
+
+func currentUsername() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// run executes a command and folds its output into the error on failure
+func run(name string, args ...string) error {
+	out, err := runCommand(name, args...)
+	if err != nil {
+		if out == "" {
+			out = err.Error()
+		}
+		return fmt.Errorf("%s %s: %s", name, strings.Join(args, " "), out)
+	}
+	return nil
+}
+
+// runPrivileged runs a command as root — directly when we already are,
+// otherwise through non-interactive sudo since there is no terminal to prompt in.
+func runPrivileged(name string, args ...string) error {
+	if os.Geteuid() == 0 {
+		return run(name, args...)
+	}
+	if err := run("sudo", append([]string{"-n", name}, args...)...); err != nil {
+		return fmt.Errorf("%v (needs passwordless sudo)", err)
+	}
+	return nil
+}
+
+func runPrivilegedOutput(name string, args ...string) (string, error) {
+	if os.Geteuid() == 0 {
+		return runCommand(name, args...)
+	}
+	return runCommand("sudo", append([]string{"-n", name}, args...)...)
+}
+
+// writePrivilegedFile writes a file that may live under /etc
+func writePrivilegedFile(path, content string, mode os.FileMode) error {
+	if os.Geteuid() == 0 {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		return os.WriteFile(path, []byte(content), mode)
+	}
+	tmp, err := os.CreateTemp("", "claw-setup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+	if err := runPrivileged("mkdir", "-p", filepath.Dir(path)); err != nil {
+		return err
+	}
+	return runPrivileged("install", "-m", fmt.Sprintf("%o", mode), tmp.Name(), path)
+}
+
+func removePrivilegedFile(path string) error {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+	return runPrivileged("rm", "-rf", path)
+}
+
+func followFile(lines int, paths ...string) []string {
+	return append([]string{"tail", "-n", fmt.Sprint(lines), "-F"}, paths...)
+}
+
+func tailFile(path string, lines int) (string, error) {
+	out, err := runCommand("tail", "-n", fmt.Sprint(lines), path)
+	if err != nil {
+		return "", fmt.Errorf("could not read %s: %s", path, out)
+	}
+	return out, nil
+}
+
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`!*?&;|<>(){}[]#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
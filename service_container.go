@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContainerSettings is a container deployment: Engine is docker or podman,
+// Method is compose, run or quadlet (podman only).
+type ContainerSettings struct {
+	Engine string `json:"engine"`
+	Method string `json:"method"`
+	Image  string `json:"image"`
+}
+
+// containerManager runs the agent's image instead of a native binary. The
+// container is always named serviceName, so inspect and logs work the same
+// for every method; with quadlet a generated systemd unit supervises it.
+type containerManager struct {
+	ContainerSettings
+}
+
+// wizardDir is where claw-setup keeps its own files, whichever runtime
+func wizardDir() string {
+	return filepath.Dir(getSettingsPath())
+}
+
+// containerEnvPath is the environment file in the KEY=value form engines
+// read: they take quotes literally, so picoclaw.env can't be used as-is
+func containerEnvPath() string {
+	return filepath.Join(wizardDir(), "container.env")
+}
+
+// ── Containers ───────────────────────────────────────────────────────────────
+
+func (m containerManager) Name() string { return m.Engine + "-" + m.Method }
+
+func (m containerManager) Definition(spec ServiceSpec) (string, string) {
+	switch m.Method {
+	case "compose":
+		return filepath.Join(wizardDir(), "compose.yaml"), m.compose(spec)
+	case "quadlet":
+		dir := filepath.Join(spec.Home, ".config", "containers", "systemd")
+		if os.Geteuid() == 0 {
+			dir = "/etc/containers/systemd"
+		}
+		return filepath.Join(dir, serviceName+".container"), m.quadletUnit(spec)
+	}
+	return filepath.Join(wizardDir(), "container-run.sh"), m.runScript(spec)
+}
+
+func (m containerManager) compose(spec ServiceSpec) string {
+	guest := currentRuntime().ContainerDataDir()
+	var args []string
+	for _, a := range spec.Args {
+		args = append(args, strconv.Quote(a))
+	}
+	user := "user: " + strconv.Quote(m.hostUser())
+	if m.Engine == "podman" && os.Geteuid() != 0 {
+		user = "userns_mode: keep-id"
+	}
+	return fmt.Sprintf(`# Managed by claw-setup
+services:
+  %s:
+    image: %s
+    container_name: %s
+    command: [%s]
+    restart: %s
+    %s
+    network_mode: host
+    env_file: %s
+    environment:
+      HOME: %s
+    volumes:
+      - %s
+`, serviceName, strconv.Quote(m.Image), serviceName, strings.Join(args, ", "), m.restartPolicy(), user,
+		strconv.Quote(containerEnvPath()), filepath.Dir(guest), strconv.Quote(spec.DataDir+":"+guest))
+}
+
+func (m containerManager) runScript(spec ServiceSpec) string {
+	guest := currentRuntime().ContainerDataDir()
+	args := []string{m.Engine, "run", "-d", "--name", serviceName, "--restart", m.restartPolicy()}
+	args = append(args, m.userArgs()...)
+	args = append(args, "--network", "host", "--env-file", containerEnvPath(), "-e", "HOME="+filepath.Dir(guest),
+		"-v", spec.DataDir+":"+guest, m.Image)
+	args = append(args, spec.Args...)
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	return "#!/bin/sh\n# Managed by claw-setup\nexec " + strings.Join(quoted, " ") + "\n"
+}
+
+// quadletUnit is a podman .container file; systemd's generator turns it
+// into picoclaw.service, which restarts and starts at boot like any unit
+func (m containerManager) quadletUnit(spec ServiceSpec) string {
+	guest := currentRuntime().ContainerDataDir()
+	wantedBy, userNS := "default.target", "UserNS=keep-id"
+	if os.Geteuid() == 0 {
+		wantedBy, userNS = "multi-user.target", "User=0:0"
+	}
+	return fmt.Sprintf(`# Managed by claw-setup
+[Unit]
+Description=%s (container)
+Wants=network-online.target
+After=network-online.target
+
+[Container]
+Image=%s
+ContainerName=%s
+Exec=%s
+%s
+Network=host
+EnvironmentFile=%s
+Environment=HOME=%s
+Volume=%s:%s:Z
+
+[Service]
+Restart=always
+RestartSec=5
+
+[Install]
+WantedBy=%s
+`, spec.Description, m.Image, serviceName, strings.Join(spec.Args, " "), userNS, containerEnvPath(),
+		filepath.Dir(guest), spec.DataDir, guest, wantedBy)
+}
+
+// Install writes the definition and creates the container. A missing image
+// is pulled on first start; deployments pull explicitly beforehand so the
+// download shows up as job progress.
+func (m containerManager) Install(spec ServiceSpec) error {
+	if err := writeContainerEnv(spec); err != nil {
+		return fmt.Errorf("Failed to write %s: %v", containerEnvPath(), err)
+	}
+	os.MkdirAll(spec.DataDir, 0755)
+	path, content := m.Definition(spec)
+	os.MkdirAll(filepath.Dir(path), 0755)
+
+	if m.Engine == "podman" && m.Method != "quadlet" {
+		// podman has no daemon to bring containers back after a reboot;
+		// podman-restart.service starts those with --restart always
+		if err := m.quadlet().systemctl("enable", "podman-restart.service"); err != nil {
+			return fmt.Errorf("podman %s containers only come back after a reboot through podman-restart.service, which could not be enabled (%v) — use quadlet instead", m.Method, err)
+		}
+	}
+
+	switch m.Method {
+	case "compose":
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+		return m.composeCmd("up", "-d")
+	case "quadlet":
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+		return m.quadlet().systemctl("daemon-reload")
+	}
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		return err
+	}
+	run(m.Engine, "rm", "-f", serviceName)
+	return run("sh", path)
+}
+
+func (m containerManager) Uninstall() error {
+	home, _ := os.UserHomeDir()
+	path, _ := m.Definition(ServiceSpec{Home: home})
+	switch m.Method {
+	case "compose":
+		m.composeCmd("down")
+	case "quadlet":
+		m.quadlet().systemctl("stop", serviceName+".service")
+	default:
+		run(m.Engine, "rm", "-f", serviceName)
+	}
+	os.Remove(containerEnvPath())
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if m.Method == "quadlet" {
+		return m.quadlet().systemctl("daemon-reload")
+	}
+	return nil
+}
+
+func (m containerManager) Start() error {
+	switch m.Method {
+	case "compose":
+		return m.composeCmd("up", "-d")
+	case "quadlet":
+		return m.quadlet().systemctl("start", serviceName+".service")
+	}
+	return run(m.Engine, "start", serviceName)
+}
+
+func (m containerManager) Stop() error {
+	if m.Method == "quadlet" {
+		return m.quadlet().systemctl("stop", serviceName+".service")
+	}
+	return run(m.Engine, "stop", serviceName)
+}
+
+func (m containerManager) Restart() error {
+	if m.Method == "quadlet" {
+		return m.quadlet().systemctl("restart", serviceName+".service")
+	}
+	return run(m.Engine, "restart", serviceName)
+}
+
+// Status maps the container's state onto the service vocabulary the rest of
+// the wizard uses; containerState has the engine's own description.
+func (m containerManager) Status() string {
+	out, err := runCommand(m.Engine, "inspect", "-f", "{{.State.Status}} {{.State.ExitCode}}", serviceName)
+	if err != nil {
+		return "inactive"
+	}
+	state, code, _ := strings.Cut(strings.TrimSpace(out), " ")
+	switch {
+	case state == "running":
+		return "active"
+	case state == "restarting":
+		return "activating"
+	case state == "exited" && code != "0":
+		return "failed"
+	}
+	return "inactive"
+}
+
+func (m containerManager) Logs(lines int) (string, error) {
+	return runCommand(m.Engine, "logs", "--tail", strconv.Itoa(lines), serviceName)
+}
+
+func (m containerManager) FollowLogs(lines int) []string {
+	return []string{m.Engine, "logs", "-f", "--tail", strconv.Itoa(lines), serviceName}
+}
+
+// ── Container Helpers ────────────────────────────────────────────────────────
+
+func (m containerManager) quadlet() systemdManager {
+	return systemdManager{user: os.Geteuid() != 0}
+}
+
+// hostUser is the wizard's uid:gid, which the container runs as so files it
+// writes to the data directory stay the user's
+func (m containerManager) hostUser() string {
+	return fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+}
+
+// userArgs maps the container's user onto the wizard's. Rootless podman
+// does that with keep-id; docker (and rootful podman) take --user.
+func (m containerManager) userArgs() []string {
+	if m.Engine == "podman" && os.Geteuid() != 0 {
+		return []string{"--userns=keep-id"}
+	}
+	return []string{"--user", m.hostUser()}
+}
+
+// restartPolicy is "always" for podman, the only policy podman-restart.service
+// acts on at boot
+func (m containerManager) restartPolicy() string {
+	if m.Engine == "podman" {
+		return "always"
+	}
+	return "unless-stopped"
+}
+
+// composeCmd runs `docker compose` / `podman compose` on our compose file
+func (m containerManager) composeCmd(args ...string) error {
+	path, _ := m.Definition(ServiceSpec{})
+	return run(m.Engine, append([]string{"compose", "-f", path, "-p", serviceName}, args...)...)
+}
+
+// containerState is the engine's status line for the container, e.g.
+// "running (healthy) since 2024-05-01T10:00:00Z", or "" if there is none
+func (m containerManager) containerState() string {
+	out, err := runCommand(m.Engine, "inspect", "-f",
+		"{{.State.Status}}{{if .State.Health}} ({{.State.Health.Status}}){{end}} since {{.State.StartedAt}} · {{.Config.Image}}", serviceName)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// writeContainerEnv writes the service environment without HOME and PATH,
+// which describe the host rather than the container
+func writeContainerEnv(spec ServiceSpec) error {
+	env := spec.envWithFile()
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		if k != "HOME" && k != "PATH" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString("# Managed by claw-setup — container environment for the agent\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, env[k])
+	}
+	if err := os.WriteFile(containerEnvPath(), []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	return os.Chmod(containerEnvPath(), 0600)
+}
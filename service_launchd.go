@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const launchdLabel = "com.picoclaw.agent"
+
+// launchdManager runs the agent as a per-user launch agent on macOS
+type launchdManager struct{}
+
+func launchdPlistPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist")
+}
+
+func launchdLogDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".picoclaw", "logs")
+}
+
+// launchd addresses per-user agents through the gui/<uid> domain
+func launchdDomain() string {
+	return "gui/" + strconv.Itoa(os.Getuid())
+}
+
+func launchdTarget() string {
+	return launchdDomain() + "/" + launchdLabel
+}
+
+// ── launchd ──────────────────────────────────────────────────────────────────
+
+func generateLaunchdPlist(spec ServiceSpec) string {
+	logDir := launchdLogDir()
+	// A fixed PATH rather than the wizard's own, so regenerating the plist
+	// from another shell doesn't count as drift
+	env := map[string]string{"PATH": launchdPath(spec.ExecPath)}
+	for k, v := range spec.envWithFile() {
+		env[k] = v
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var envXML strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&envXML, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", xmlEscape(k), xmlEscape(env[k]))
+	}
+
+	var argsXML strings.Builder
+	for _, a := range append([]string{spec.ExecPath}, spec.Args...) {
+		fmt.Fprintf(&argsXML, "\t\t<string>%s</string>\n", xmlEscape(a))
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>WorkingDirectory</key>
+	<string>%s</string>
+	<key>EnvironmentVariables</key>
+	<dict>
+%s	</dict>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>ThrottleInterval</key>
+	<integer>5</integer>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`,
+		xmlEscape(launchdLabel),
+		argsXML.String(),
+		xmlEscape(spec.WorkDir),
+		envXML.String(),
+		xmlEscape(filepath.Join(logDir, "picoclaw.log")),
+		xmlEscape(filepath.Join(logDir, "picoclaw.err.log")),
+	)
+}
+
+func launchdPath(execPath string) string {
+	dirs := []string{filepath.Dir(execPath)}
+	for _, d := range []string{"/usr/local/bin", "/opt/homebrew/bin", "/usr/bin", "/bin", "/usr/sbin", "/sbin"} {
+		if d != dirs[0] {
+			dirs = append(dirs, d)
+		}
+	}
+	return strings.Join(dirs, ":")
+}
+
+func (launchdManager) Name() string { return "launchd" }
+
+func (launchdManager) Definition(spec ServiceSpec) (string, string) {
+	return launchdPlistPath(), generateLaunchdPlist(spec)
+}
+
+func (launchdManager) Install(spec ServiceSpec) error {
+	plistPath := launchdPlistPath()
+	os.MkdirAll(filepath.Dir(plistPath), 0755)
+	os.MkdirAll(launchdLogDir(), 0755)
+
+	// 0600 since EnvironmentVariables may carry secrets from the env file
+	if err := os.WriteFile(plistPath, []byte(generateLaunchdPlist(spec)), 0600); err != nil {
+		return fmt.Errorf("Failed to write plist: %v", err)
+	}
+	os.Chmod(plistPath, 0600)
+	if out, err := runCommand("plutil", "-lint", plistPath); err != nil {
+		return fmt.Errorf("Generated plist is invalid: %s", out)
+	}
+
+	// bootstrap fails if the agent is already loaded — unload any old copy first
+	exec.Command("launchctl", "bootout", launchdTarget()).Run()
+	if err := run("launchctl", "bootstrap", launchdDomain(), plistPath); err != nil {
+		return err
+	}
+	exec.Command("launchctl", "enable", launchdTarget()).Run()
+	return nil
+}
+
+func (launchdManager) Uninstall() error {
+	exec.Command("launchctl", "bootout", launchdTarget()).Run()
+	if err := os.Remove(launchdPlistPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (launchdManager) loaded() bool {
+	_, err := runCommand("launchctl", "print", launchdTarget())
+	return err == nil
+}
+
+func (m launchdManager) Start() error {
+	if !m.loaded() {
+		return run("launchctl", "bootstrap", launchdDomain(), launchdPlistPath())
+	}
+	return run("launchctl", "kickstart", launchdTarget())
+}
+
+// Stop unloads the agent — with KeepAlive set, killing the process would
+// only have launchd start it again.
+func (launchdManager) Stop() error {
+	return run("launchctl", "bootout", launchdTarget())
+}
+
+func (m launchdManager) Restart() error {
+	if _, err := os.Stat(launchdPlistPath()); err != nil {
+		return fmt.Errorf("Launch agent not installed — install the service first")
+	}
+	if !m.loaded() {
+		return run("launchctl", "bootstrap", launchdDomain(), launchdPlistPath())
+	}
+	return run("launchctl", "kickstart", "-k", launchdTarget())
+}
+
+// Status reads `launchctl print` — "state = running" means the agent
+// process is up, anything else (or an error) means it is not.
+func (launchdManager) Status() string {
+	out, err := runCommand("launchctl", "print", launchdTarget())
+	if err != nil {
+		return "inactive"
+	}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "state = ") {
+			if strings.TrimPrefix(line, "state = ") == "running" {
+				return "active"
+			}
+			return "inactive"
+		}
+	}
+	return "inactive"
+}
+
+func (launchdManager) Logs(lines int) (string, error) {
+	return tailFile(filepath.Join(launchdLogDir(), "picoclaw.log"), lines)
+}
+
+// stdout and stderr go to separate files; follow both (tail -q drops the
+// "==> file <==" headers so lines interleave cleanly)
+func (launchdManager) FollowLogs(lines int) []string {
+	args := followFile(lines, filepath.Join(launchdLogDir(), "picoclaw.log"), filepath.Join(launchdLogDir(), "picoclaw.err.log"))
+	return append(args[:1], append([]string{"-q"}, args[1:]...)...)
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// openrcManager runs the agent under OpenRC's supervise-daemon, as used on
+// Alpine-based Pi images.
+type openrcManager struct{}
+
+const (
+	openrcScriptPath = "/etc/init.d/" + serviceName
+	// openrc-run loads conf.d itself; root-only since it carries API keys
+	openrcConfPath = "/etc/conf.d/" + serviceName
+	openrcLogPath  = "/var/log/" + serviceName + ".log"
+)
+
+func (openrcManager) Name() string { return "openrc" }
+
+func (m openrcManager) Definition(spec ServiceSpec) (string, string) {
+	return openrcScriptPath, m.script(spec)
+}
+
+func (openrcManager) script(spec ServiceSpec) string {
+	return fmt.Sprintf(`#!/sbin/openrc-run
+
+name="%s"
+description="%s"
+command=%s
+command_args="%s"
+command_user="%s"
+directory=%s
+supervisor=supervise-daemon
+respawn_delay=5
+respawn_max=0
+output_log="%s"
+error_log="%s"
+
+depend() {
+	need net
+	after firewall
+}
+`, serviceName, spec.Description, shellQuote(spec.ExecPath), strings.Join(spec.Args, " "),
+		spec.User, shellQuote(spec.WorkDir), openrcLogPath, openrcLogPath)
+}
+
+func (m openrcManager) Install(spec ServiceSpec) error {
+	if err := writePrivilegedFile(openrcScriptPath, m.script(spec), 0755); err != nil {
+		return fmt.Errorf("Failed to write init script: %v", err)
+	}
+	if err := writePrivilegedFile(openrcConfPath, spec.envExports(), 0600); err != nil {
+		return fmt.Errorf("Failed to write %s: %v", openrcConfPath, err)
+	}
+	// supervise-daemon drops privileges before opening the log, so it must be writable
+	runPrivileged("touch", openrcLogPath)
+	runPrivileged("chown", spec.User, openrcLogPath)
+	return runPrivileged("rc-update", "add", serviceName, "default")
+}
+
+func (openrcManager) Uninstall() error {
+	runPrivileged("rc-service", serviceName, "stop")
+	runPrivileged("rc-update", "del", serviceName, "default")
+	if err := removePrivilegedFile(openrcConfPath); err != nil {
+		return err
+	}
+	return removePrivilegedFile(openrcScriptPath)
+}
+
+func (openrcManager) Start() error   { return runPrivileged("rc-service", serviceName, "start") }
+func (openrcManager) Stop() error    { return runPrivileged("rc-service", serviceName, "stop") }
+func (openrcManager) Restart() error { return runPrivileged("rc-service", serviceName, "restart") }
+
+func (openrcManager) Status() string {
+	out, err := runCommand("rc-service", serviceName, "status")
+	if err == nil && strings.Contains(out, "started") {
+		return "active"
+	}
+	return "inactive"
+}
+
+func (openrcManager) Logs(lines int) (string, error) {
+	return tailFile(openrcLogPath, lines)
+}
+
+func (openrcManager) FollowLogs(lines int) []string {
+	return followFile(lines, openrcLogPath)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// runitManager runs the agent as a runit service with an svlogd logger
+type runitManager struct{}
+
+const (
+	runitSvDir  = "/etc/sv/" + serviceName
+	runitLogDir = "/var/log/" + serviceName
+	// runitEnvDir holds one root-only file per variable, read by chpst -e
+	runitEnvDir = runitSvDir + "/env"
+)
+
+// runitServiceDir is the directory runsvdir scans: /var/service on Void,
+// /etc/service on Debian's runit package.
+func runitServiceDir() string {
+	for _, dir := range []string{"/var/service", "/etc/service", "/service"} {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return ""
+}
+
+func (runitManager) Name() string { return "runit" }
+
+func (m runitManager) Definition(spec ServiceSpec) (string, string) {
+	return filepath.Join(runitSvDir, "run"), m.runScript(spec)
+}
+
+func (runitManager) runScript(spec ServiceSpec) string {
+	return fmt.Sprintf(`#!/bin/sh
+exec 2>&1
+cd %s || exit 1
+exec chpst -e %s -u %s %s
+`, shellQuote(spec.WorkDir), runitEnvDir, spec.User, spec.command())
+}
+
+// writeEnvDir replaces the envdir with the spec's variables, so removed
+// ones don't linger
+func (runitManager) writeEnvDir(spec ServiceSpec) error {
+	if err := removePrivilegedFile(runitEnvDir); err != nil {
+		return err
+	}
+	if err := runPrivileged("mkdir", "-p", "-m", "0700", runitEnvDir); err != nil {
+		return err
+	}
+	env := spec.envWithFile()
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		if envKeyPattern.MatchString(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := writePrivilegedFile(filepath.Join(runitEnvDir, k), env[k]+"\n", 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m runitManager) Install(spec ServiceSpec) error {
+	if err := m.writeEnvDir(spec); err != nil {
+		return fmt.Errorf("Failed to write environment: %v", err)
+	}
+	if err := writePrivilegedFile(filepath.Join(runitSvDir, "run"), m.runScript(spec), 0755); err != nil {
+		return fmt.Errorf("Failed to write run script: %v", err)
+	}
+	logRun := "#!/bin/sh\nexec svlogd -tt " + runitLogDir + "\n"
+	if err := writePrivilegedFile(filepath.Join(runitSvDir, "log", "run"), logRun, 0755); err != nil {
+		return fmt.Errorf("Failed to write log script: %v", err)
+	}
+	runPrivileged("mkdir", "-p", runitLogDir)
+
+	link := filepath.Join(runitServiceDir(), serviceName)
+	if _, err := os.Lstat(link); os.IsNotExist(err) {
+		if err := runPrivileged("ln", "-s", runitSvDir, link); err != nil {
+			return err
+		}
+	}
+	// runsvdir picks up new services every few seconds; wait for supervise/
+	for i := 0; i < 10; i++ {
+		if _, err := os.Stat(filepath.Join(runitSvDir, "supervise")); err == nil {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	return nil
+}
+
+func (runitManager) Uninstall() error {
+	runPrivileged("sv", "stop", serviceName)
+	if err := removePrivilegedFile(filepath.Join(runitServiceDir(), serviceName)); err != nil {
+		return err
+	}
+	return removePrivilegedFile(runitSvDir)
+}
+
+func (runitManager) Start() error   { return runPrivileged("sv", "start", serviceName) }
+func (runitManager) Stop() error    { return runPrivileged("sv", "stop", serviceName) }
+func (runitManager) Restart() error { return runPrivileged("sv", "restart", serviceName) }
+
+func (runitManager) Status() string {
+	out, err := runPrivilegedOutput("sv", "status", serviceName)
+	if err == nil && strings.HasPrefix(out, "run:") {
+		return "active"
+	}
+	return "inactive"
+}
+
+func (runitManager) Logs(lines int) (string, error) {
+	return tailFile(filepath.Join(runitLogDir, "current"), lines)
+}
+
+func (runitManager) FollowLogs(lines int) []string {
+	return followFile(lines, filepath.Join(runitLogDir, "current"))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// supervisordManager runs the agent as a supervisord program
+type supervisordManager struct{}
+
+const supervisordLogPath = "/var/log/" + serviceName + ".log"
+
+// supervisordConfPath follows the include directory of the installed
+// package: conf.d/*.conf on Debian, supervisord.d/*.ini on RHEL and Alpine.
+func supervisordConfPath() string {
+	for _, p := range []string{"/etc/supervisor/conf.d", "/etc/supervisord.d", "/etc/supervisor.d"} {
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			if strings.HasSuffix(p, "conf.d") {
+				return p + "/" + serviceName + ".conf"
+			}
+			return p + "/" + serviceName + ".ini"
+		}
+	}
+	return "/etc/supervisor/conf.d/" + serviceName + ".conf"
+}
+
+func (supervisordManager) Name() string { return "supervisord" }
+
+func (m supervisordManager) Definition(spec ServiceSpec) (string, string) {
+	return supervisordConfPath(), m.program(spec)
+}
+
+func (supervisordManager) program(spec ServiceSpec) string {
+	// supervisord has no env-file support, so the file's values go inline
+	vars := spec.envWithFile()
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var env []string
+	for _, k := range keys {
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(vars[k])
+		env = append(env, supervisordEscape(fmt.Sprintf(`%s="%s"`, k, v)))
+	}
+
+	return fmt.Sprintf(`[program:%s]
+command=%s
+directory=%s
+user=%s
+environment=%s
+autostart=true
+autorestart=unexpected
+startsecs=5
+startretries=10
+redirect_stderr=true
+stdout_logfile=%s
+stdout_logfile_maxbytes=10MB
+stdout_logfile_backups=3
+`, serviceName, supervisordEscape(spec.command()), supervisordEscape(spec.WorkDir), supervisordEscape(spec.User),
+		strings.Join(env, ","), supervisordLogPath)
+}
+
+func (m supervisordManager) Install(spec ServiceSpec) error {
+	// directory= is taken literally, not shell-split, so it can't be quoted;
+	// a line break would start a new setting and edge spaces get trimmed
+	for _, v := range []string{spec.command(), spec.WorkDir, spec.User} {
+		if strings.ContainsAny(v, "\r\n") || v != strings.TrimSpace(v) {
+			return fmt.Errorf("%q can't be written to a supervisord config", v)
+		}
+	}
+	if err := writePrivilegedFile(supervisordConfPath(), m.program(spec), 0600); err != nil {
+		return fmt.Errorf("Failed to write program config: %v", err)
+	}
+	if err := runPrivileged("supervisorctl", "reread"); err != nil {
+		return err
+	}
+	return runPrivileged("supervisorctl", "update", serviceName)
+}
+
+func (supervisordManager) Uninstall() error {
+	runPrivileged("supervisorctl", "stop", serviceName)
+	if err := removePrivilegedFile(supervisordConfPath()); err != nil {
+		return err
+	}
+	runPrivileged("supervisorctl", "reread")
+	return runPrivileged("supervisorctl", "update")
+}
+
+func (supervisordManager) Start() error   { return runPrivileged("supervisorctl", "start", serviceName) }
+func (supervisordManager) Stop() error    { return runPrivileged("supervisorctl", "stop", serviceName) }
+func (supervisordManager) Restart() error { return runPrivileged("supervisorctl", "restart", serviceName) }
+
+func (supervisordManager) Status() string {
+	out, _ := runPrivilegedOutput("supervisorctl", "status", serviceName)
+	if strings.Contains(out, "RUNNING") {
+		return "active"
+	}
+	return "inactive"
+}
+
+func (supervisordManager) Logs(lines int) (string, error) {
+	return tailFile(supervisordLogPath, lines)
+}
+
+func (supervisordManager) FollowLogs(lines int) []string {
+	return followFile(lines, supervisordLogPath)
+}
+
+// supervisordEscape doubles %, which supervisord otherwise expands as
+// %(name)s interpolation in every value
+func supervisordEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// systemdManager runs the agent as a systemd unit — a --user unit by default,
+// or a system unit with User= when no user manager is available.
+type systemdManager struct {
+	user bool
+}
+
+func (m systemdManager) Name() string {
+	if m.user {
+		return "systemd-user"
+	}
+	return "systemd"
+}
+
+func (m systemdManager) unitPath() string {
+	return m.unitFilePath(serviceName + ".service")
+}
+
+func (m systemdManager) unitFilePath(name string) string {
+	if m.user {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, ".config", "systemd", "user", name)
+	}
+	return filepath.Join("/etc/systemd/system", name)
+}
+
+// writeUnitFile installs a unit file — directly for user units, via
+// sudo for system ones
+func (m systemdManager) writeUnitFile(name, content string) error {
+	path := m.unitFilePath(name)
+	if m.user {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		return os.WriteFile(path, []byte(content), 0644)
+	}
+	return writePrivilegedFile(path, content, 0644)
+}
+
+func (m systemdManager) removeUnitFile(name string) error {
+	path := m.unitFilePath(name)
+	if m.user {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return removePrivilegedFile(path)
+}
+
+func (m systemdManager) Definition(spec ServiceSpec) (string, string) {
+	return m.unitPath(), m.unit(spec)
+}
+
+func (m systemdManager) systemctl(args ...string) error {
+	if m.user {
+		return run("systemctl", append([]string{"--user"}, args...)...)
+	}
+	return runPrivileged("systemctl", args...)
+}
+
+func (m systemdManager) unit(spec ServiceSpec) string {
+	var env strings.Builder
+	keys := make([]string, 0, len(spec.Env))
+	for k := range spec.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&env, "Environment=%s\n", systemdQuote(k+"="+spec.Env[k]))
+	}
+	if spec.EnvFile != "" {
+		fmt.Fprintf(&env, "EnvironmentFile=-%s\n", spec.EnvFile)
+	}
+
+	userLine := ""
+	wantedBy := "default.target"
+	if !m.user {
+		userLine = "User=" + spec.User + "\n"
+		wantedBy = "multi-user.target"
+	}
+
+	return fmt.Sprintf(`[Unit]
+Description=%s
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=simple
+%sExecStart=%s
+Restart=on-failure
+RestartSec=5
+WorkingDirectory=%s
+%s%s
+[Install]
+WantedBy=%s
+`, spec.Description, userLine, spec.command(), spec.WorkDir, env.String(), spec.Hardening.unitLines(spec.DataDir, spec.Workspace), wantedBy)
+}
+
+// verify runs systemd-analyze over the unit before it is installed, so a bad
+// hardening value is reported instead of leaving a unit that won't start.
+func (m systemdManager) verify(content string) error {
+	if !hasCommand("systemd-analyze") {
+		return nil
+	}
+	dir, err := os.MkdirTemp("", "claw-setup-unit-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, serviceName+".service")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+
+	args := []string{"verify", path}
+	if m.user {
+		args = append([]string{"--user"}, args...)
+	}
+	if out, err := runCommand("systemd-analyze", args...); err != nil {
+		return fmt.Errorf("Unit failed systemd-analyze verify: %s", out)
+	}
+	return nil
+}
+
+func (m systemdManager) Install(spec ServiceSpec) error {
+	content := m.unit(spec)
+	if err := m.verify(content); err != nil {
+		return err
+	}
+	if err := m.writeUnitFile(serviceName+".service", content); err != nil {
+		return fmt.Errorf("Failed to write service file: %v", err)
+	}
+
+	if err := m.systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	return m.systemctl("enable", serviceName)
+}
+
+func (m systemdManager) Uninstall() error {
+	m.systemctl("disable", "--now", serviceName)
+	if err := m.removeUnitFile(serviceName + ".service"); err != nil {
+		return err
+	}
+	return m.systemctl("daemon-reload")
+}
+
+func (m systemdManager) Start() error   { return m.systemctl("start", serviceName) }
+func (m systemdManager) Stop() error    { return m.systemctl("stop", serviceName) }
+func (m systemdManager) Restart() error { return m.systemctl("restart", serviceName) }
+
+func (m systemdManager) Status() string {
+	args := []string{"is-active", serviceName}
+	if m.user {
+		args = append([]string{"--user"}, args...)
+	}
+	out, err := runCommand("systemctl", args...)
+	if err == nil && strings.TrimSpace(out) == "active" {
+		return "active"
+	}
+	return "inactive"
+}
+
+func (m systemdManager) Logs(lines int) (string, error) {
+	args := []string{"-u", serviceName, "-n", fmt.Sprint(lines), "--no-pager", "-o", "short-iso"}
+	if m.user {
+		args = append([]string{"--user"}, args...)
+	}
+	out, err := runCommand("journalctl", args...)
+	if err != nil {
+		return "", fmt.Errorf("journalctl failed: %s", out)
+	}
+	return out, nil
+}
+
+func (m systemdManager) FollowLogs(lines int) []string {
+	args := []string{"journalctl", "-u", serviceName, "-n", fmt.Sprint(lines), "-f", "--no-pager", "-o", "short-iso"}
+	if m.user {
+		args = append(args[:1], append([]string{"--user"}, args[1:]...)...)
+	}
+	return args
+}
+
+// systemdQuote makes a KEY=value a single double-quoted unit file word:
+// backslashes, quotes and newlines are escaped as systemd unquotes them, and
+// % is doubled so specifiers like %h aren't expanded
+func systemdQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "%", "%%").Replace(s)
+	return `"` + s + `"`
+}
+
+// lingerStatus reports whether logind keeps the user manager — and with it
+// the agent — running while nobody is logged in: "enabled" or "disabled",
+// or "" when the agent isn't a systemd user unit and linger doesn't apply.
+func lingerStatus(mgr ServiceManager) string {
+	// A rootless quadlet container is a user unit too
+	if cm, ok := mgr.(containerManager); ok && cm.Method == "quadlet" {
+		mgr = cm.quadlet()
+	}
+	if m, ok := mgr.(systemdManager); !ok || !m.user {
+		return ""
+	}
+	username := currentUsername()
+	if out, err := runCommand("loginctl", "show-user", username, "--property=Linger", "--value"); err == nil {
+		if out == "yes" {
+			return "enabled"
+		}
+		return "disabled"
+	}
+	// show-user fails when the user has no session; the flag file is the source of truth
+	if _, err := os.Stat(filepath.Join("/var/lib/systemd/linger", username)); err == nil {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// enableLinger starts the user manager at boot. Polkit usually lets users
+// enable it for themselves; otherwise fall back to sudo.
+func enableLinger() error {
+	username := currentUsername()
+	if err := run("loginctl", "enable-linger", username); err == nil {
+		return nil
+	}
+	return runPrivileged("loginctl", "enable-linger", username)
+}
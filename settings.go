@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// SetupSettings holds the wizard's own choices — things picoclaw's
+// config.json has no place for, but that must survive a restart so the
+// service can be regenerated the same way.
+type SetupSettings struct {
+	Hardening *ServiceHardening `json:"hardening,omitempty"`
+	Watchdog  *WatchdogSettings `json:"watchdog,omitempty"`
+	// PinnedVersion is a release tag installs stick to; "" follows latest
+	PinnedVersion string `json:"pinned_version,omitempty"`
+	// Runtime is the AgentRuntime ID being set up; "" means picoclaw
+	Runtime string `json:"runtime,omitempty"`
+	// Container switches the service to a container deployment; nil runs
+	// the native binary under the init system
+	Container *ContainerSettings `json:"container,omitempty"`
+}
+
+// ------- Settings Helpers -------
+
+func getSettingsPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".picoclaw", "claw-setup.json")
+}
+
+func readSettings() SetupSettings {
+	var s SetupSettings
+	data, err := os.ReadFile(getSettingsPath())
+	if err != nil {
+		return s
+	}
+	json.Unmarshal(data, &s)
+	return s
+}
+
+func writeSettings(s SetupSettings) error {
+	path := getSettingsPath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	data, err := json.MarshalIndent(s, "", " ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const slackAPI = "https://slack.com/api/"
+
+// Bot scopes picoclaw needs to read DMs, mentions and channel messages and
+// to reply in them.
+var slackBotScopes = []string{
+	"app_mentions:read",
+	"channels:history",
+	"channels:read",
+	"chat:write",
+	"groups:history",
+	"groups:read",
+	"im:history",
+	"im:read",
+	"im:write",
+	"users:read",
+}
+
+// ── Slack ────────────────────────────────────────────────────────────────────
+
+func handleSlackManifest(w http.ResponseWriter, r *http.Request) {
+	r.ParseMultipartForm(10 << 20)
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		name = "PicoClaw"
+	}
+
+	manifest := slackManifest(name)
+	b, _ := json.Marshal(manifest)
+	jsonResponse(w, map[string]interface{}{
+		"ok":         true,
+		"manifest":   manifest,
+		"create_url": "https://api.slack.com/apps?new_app=1&manifest_json=" + url.QueryEscape(string(b)),
+	})
+}
+
+func handleValidateSlack(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	botToken := strings.TrimSpace(r.FormValue("bot_token"))
+	appToken := strings.TrimSpace(r.FormValue("app_token"))
+	if botToken == "" || appToken == "" {
+		errorResponse(w, "bot_token and app_token are required")
+		return
+	}
+	if !strings.HasPrefix(botToken, "xoxb-") {
+		errorResponse(w, "The bot token should start with xoxb-")
+		return
+	}
+	if !strings.HasPrefix(appToken, "xapp-") {
+		errorResponse(w, "The app-level token should start with xapp-")
+		return
+	}
+
+	auth, err := slackAuthTest(botToken)
+	if err != nil {
+		errorResponse(w, "Bot token: "+err.Error())
+		return
+	}
+	if err := slackOpenConnection(appToken); err != nil {
+		errorResponse(w, "App token: "+err.Error()+" (is Socket Mode enabled with connections:write?)")
+		return
+	}
+
+	rt := currentRuntime()
+	cfg := readConfig(rt)
+	if cfg.Channels == nil {
+		cfg.Channels = make(map[string]map[string]interface{})
+	}
+	sc := cfg.Channels["slack"]
+	if sc == nil {
+		sc = make(map[string]interface{})
+	}
+	sc["enabled"] = true
+	sc["bot_token"] = botToken
+	sc["app_token"] = appToken
+	cfg.Channels["slack"] = sc
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
+
+	okResponse(w, "Bot @"+auth.User+" connected to "+auth.Team, map[string]interface{}{
+		"team": auth.Team,
+		"user": auth.User,
+	})
+}
+
+func handleSlackDirectory(w http.ResponseWriter, r *http.Request) {
+	token := slackBotToken()
+	if token == "" {
+		errorResponse(w, "Slack not configured yet")
+		return
+	}
+
+	channels, err := listSlackChannels(token)
+	if err != nil {
+		errorResponse(w, "Failed to list channels: "+err.Error())
+		return
+	}
+	users, err := listSlackUsers(token)
+	if err != nil {
+		errorResponse(w, "Failed to list users: "+err.Error())
+		return
+	}
+	jsonResponse(w, map[string]interface{}{
+		"ok":       true,
+		"channels": channels,
+		"users":    users,
+	})
+}
+
+func handleSaveSlackAccess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+
+	users := splitList(r.FormValue("user_ids"))
+	channels := splitList(r.FormValue("channel_ids"))
+	if len(users) == 0 {
+		errorResponse(w, "Pick at least one user who may talk to the agent")
+		return
+	}
+
+	rt := currentRuntime()
+	cfg := readConfig(rt)
+	if cfg.Channels == nil {
+		cfg.Channels = make(map[string]map[string]interface{})
+	}
+	if cfg.Channels["slack"] == nil {
+		cfg.Channels["slack"] = make(map[string]interface{})
+	}
+	cfg.Channels["slack"]["allowFrom"] = users
+	cfg.Channels["slack"]["allowChannels"] = channels
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
+	okResponse(w, fmt.Sprintf("Saved %d user(s) and %d channel(s)", len(users), len(channels)), nil)
+}
+
+func handlePingSlack(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	target := strings.TrimSpace(r.FormValue("target"))
+	if target == "" {
+		errorResponse(w, "target is required")
+		return
+	}
+
+	token := slackBotToken()
+	if token == "" {
+		errorResponse(w, "No token found — complete token validation first")
+		return
+	}
+
+	ok, msg := sendSlackPing(token, target)
+	jsonResponse(w, map[string]interface{}{
+		"ok":      ok,
+		"message": msg,
+	})
+}
+
+func slackBotToken() string {
+	cfg := readConfig(currentRuntime())
+	token, _ := cfg.Channels["slack"]["bot_token"].(string)
+	return token
+}
+
+// ── Slack API ────────────────────────────────────────────────────────────────
+
+type SlackAuth struct {
+	Team   string `json:"team"`
+	User   string `json:"user"`
+	UserID string `json:"user_id"`
+}
+
+type SlackChannel struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	IsPrivate bool   `json:"is_private"`
+	IsMember  bool   `json:"is_member"`
+}
+
+type SlackUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	RealName string `json:"real_name"`
+}
+
+func slackManifest(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"display_information": map[string]interface{}{
+			"name":        name,
+			"description": "Your digital twin, powered by PicoClaw",
+		},
+		"features": map[string]interface{}{
+			"app_home": map[string]interface{}{
+				"messages_tab_enabled":           true,
+				"messages_tab_read_only_enabled": false,
+			},
+			"bot_user": map[string]interface{}{
+				"display_name":  name,
+				"always_online": true,
+			},
+		},
+		"oauth_config": map[string]interface{}{
+			"scopes": map[string]interface{}{
+				"bot": slackBotScopes,
+			},
+		},
+		"settings": map[string]interface{}{
+			"event_subscriptions": map[string]interface{}{
+				"bot_events": []string{"app_mention", "message.channels", "message.groups", "message.im"},
+			},
+			"interactivity":       map[string]interface{}{"is_enabled": true},
+			"socket_mode_enabled": true,
+		},
+	}
+}
+
+// slackCall POSTs a form to a Web API method and decodes the response into
+// out. Slack reports failures as ok=false with an error code, not HTTP status.
+func slackCall(method, token string, params url.Values, out interface{}) error {
+	req, _ := http.NewRequest("POST", slackAPI+method, strings.NewReader(params.Encode()))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Connection failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fmt.Errorf("Slack error %d", resp.StatusCode)
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	json.Unmarshal(raw, &status)
+	if !status.OK {
+		return fmt.Errorf("%s", status.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(raw, out)
+	}
+	return nil
+}
+
+func slackAuthTest(token string) (SlackAuth, error) {
+	var auth SlackAuth
+	err := slackCall("auth.test", token, url.Values{}, &auth)
+	return auth, err
+}
+
+// slackOpenConnection proves the app-level token works for Socket Mode.
+// The returned websocket URL is single-use and simply discarded.
+func slackOpenConnection(appToken string) error {
+	return slackCall("apps.connections.open", appToken, url.Values{}, nil)
+}
+
+func listSlackChannels(token string) ([]SlackChannel, error) {
+	var result struct {
+		Channels []SlackChannel `json:"channels"`
+	}
+	params := url.Values{
+		"types":            {"public_channel,private_channel"},
+		"exclude_archived": {"true"},
+		"limit":            {"200"},
+	}
+	err := slackCall("conversations.list", token, params, &result)
+	return result.Channels, err
+}
+
+func listSlackUsers(token string) ([]SlackUser, error) {
+	var result struct {
+		Members []struct {
+			SlackUser
+			IsBot   bool `json:"is_bot"`
+			Deleted bool `json:"deleted"`
+		} `json:"members"`
+	}
+	if err := slackCall("users.list", token, url.Values{"limit": {"200"}}, &result); err != nil {
+		return nil, err
+	}
+
+	var users []SlackUser
+	for _, m := range result.Members {
+		if m.IsBot || m.Deleted || m.ID == "USLACKBOT" {
+			continue
+		}
+		users = append(users, m.SlackUser)
+	}
+	return users, nil
+}
+
+func sendSlackPing(token, target string) (bool, string) {
+	params := url.Values{
+		"channel": {target},
+		"text":    {"🟢 Ping from claw-setup!\n\nYour PicoClaw agent is configured and ready."},
+	}
+	err := slackCall("chat.postMessage", token, params, nil)
+	if err == nil {
+		return true, "Message posted — check Slack"
+	}
+	switch err.Error() {
+	case "not_in_channel":
+		return false, "The bot isn't in that channel — type /invite @yourbot in it first"
+	case "channel_not_found":
+		return false, "Channel or user not found"
+	}
+	return false, err.Error()
+}
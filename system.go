@@ -14,6 +14,8 @@ import (
 
 // SystemStatus that holds everything we check on the Pi
 type SystemStatus struct {
+	Runtime         string `json:"runtime"`
+	RuntimeName     string `json:"runtime_name"`
 	PicoclawInstalled	bool	`json:"picoclaw_installed"`
 	PicoclawVersion	string	`json:"picoclaw_version"`
 	DiskSpace	string	`json:"disk_space"`
@@ -26,12 +28,39 @@ type SystemStatus struct {
 	ActiveProvider  string `json:"active_provider"`
 	TelegramToken   string `json:"telegram_token"`
 	TelegramUser    string `json:"telegram_user"`
+	HasDiscord      bool   `json:"has_discord"`
+	DiscordToken    string `json:"discord_token"`
+	DiscordUsers    []string `json:"discord_users"`
+	HasSlack        bool     `json:"has_slack"`
+	SlackToken      string   `json:"slack_token"`
+	SlackUsers      []string `json:"slack_users"`
+	SlackChannels   []string `json:"slack_channels"`
+	HasWhatsApp     bool     `json:"has_whatsapp"`
+	WhatsAppNumbers []string `json:"whatsapp_numbers"`
+	HasMatrix       bool     `json:"has_matrix"`
+	MatrixUserID    string   `json:"matrix_user_id"`
+	MatrixRoom      string   `json:"matrix_room"`
+	MatrixUsers     []string `json:"matrix_users"`
+	HasEmail        bool     `json:"has_email"`
+	EmailAddress    string   `json:"email_address"`
+	EmailSenders    []string `json:"email_senders"`
+	Channels        []ChannelStatus `json:"channels"`
+	HealthyChannels int      `json:"healthy_channels"`
+	LatestVersion   string `json:"latest_version"`
+	UpdateAvailable bool   `json:"update_available"`
+	SudoAvailable   bool   `json:"sudo_available"`
+	InstallDir      string `json:"install_dir"`
 	ServiceStatus	string	`json:"service_status"`
+	ServiceManager  string `json:"service_manager"`
+	ContainerStatus string `json:"container_status"`
+	Linger          string `json:"linger"`
+	ServiceDrift    bool   `json:"service_drift"`
+	DriftReason     string `json:"drift_reason"`
 	OS		string	`json:"os"`
 	Checklist	struct	{
 		System	bool	`json:"system"`
 		Provider	bool	`json:"provider"`
-		Telegram	bool	`json:"telegram"`
+		Channels	bool	`json:"channels"`
 		Soul	bool	`json:"soul"`
 		Service	bool	`json:"service"`
 		}	`json:"checklist"`
@@ -45,15 +74,29 @@ func handleSystemCheck(w http.ResponseWriter, r *http.Request) {
 
 func buildSystemStatus() SystemStatus {
 	var s SystemStatus
-	// Check PicoClaw
-	path, err := exec.LookPath("picoclaw")
+	// Check the agent — PicoClaw unless another runtime was picked
+	rt := currentRuntime()
+	s.Runtime, s.RuntimeName = rt.ID(), rt.Name()
+	path, err := exec.LookPath(rt.Binary())
 	if err == nil && path != "" {
 		s.PicoclawInstalled = true
-		out, _ := runCommand("picoclaw", "version")
+		out := rt.Version(path)
 		if out == "" {
 			out = "installed"
 		}
 		s.PicoclawVersion = out
+		// The release browser only knows PicoClaw's GitHub releases
+		if rt.ID() == "picoclaw" {
+			s.LatestVersion, s.UpdateAvailable = updateAvailable(out, cachedReleases())
+		}
+	} else if c := readSettings().Container; c != nil {
+		// The image carries its own binary
+		s.PicoclawInstalled = true
+		s.PicoclawVersion = "Container image " + c.Image
+	} else {
+		// Only worth probing sudo when the install card will be shown
+		s.SudoAvailable = sudoAvailable()
+		s.InstallDir = defaultInstallDir()
 	}
 
 	// Disk Space
@@ -72,10 +115,10 @@ func buildSystemStatus() SystemStatus {
 	s.RAM = getRAM()
 
 	// Config
-	configPath := getConfigPath()
+	configPath := rt.ConfigPath()
 	if _, err := os.Stat(configPath); err == nil {
 		s.ConfigExists = true
-		cfg := readConfig()
+		cfg := readConfig(rt)
 		if len(cfg.Providers) > 0 {
 			s.HasProvider = true
 			for name := range cfg.Providers {
@@ -104,6 +147,52 @@ func buildSystemStatus() SystemStatus {
 				}
 			}
 		}
+
+		if dc, ok := cfg.Channels["discord"]; ok {
+			if token, ok := dc["token"].(string); ok && token != "" {
+				s.HasDiscord = true
+				s.DiscordToken = maskSecret(token)
+			}
+			s.DiscordUsers = stringList(dc["allowFrom"])
+		}
+
+		if sc, ok := cfg.Channels["slack"]; ok {
+			if token, ok := sc["bot_token"].(string); ok && token != "" {
+				s.HasSlack = true
+				s.SlackToken = maskSecret(token)
+			}
+			s.SlackUsers = stringList(sc["allowFrom"])
+			s.SlackChannels = stringList(sc["allowChannels"])
+		}
+
+		if wa, ok := cfg.Channels["whatsapp"]; ok {
+			s.HasWhatsApp, _ = wa["enabled"].(bool)
+			s.WhatsAppNumbers = stringList(wa["allowFrom"])
+		}
+
+		if mx, ok := cfg.Channels["matrix"]; ok {
+			if token, ok := mx["access_token"].(string); ok && token != "" {
+				s.HasMatrix = true
+			}
+			s.MatrixUserID, _ = mx["user_id"].(string)
+			s.MatrixRoom, _ = mx["room_id"].(string)
+			s.MatrixUsers = stringList(mx["allowFrom"])
+		}
+
+		if em, ok := cfg.Channels["email"]; ok {
+			if addr, ok := em["address"].(string); ok && addr != "" {
+				s.HasEmail = true
+				s.EmailAddress = addr
+			}
+			s.EmailSenders = stringList(em["allowFrom"])
+		}
+
+		s.Channels = cachedChannels(cfg)
+		for _, ch := range s.Channels {
+			if ch.Enabled && ch.Healthy {
+				s.HealthyChannels++
+			}
+		}
 	}
 	//	if tg, ok := cfg.Channels["telegram"]; ok {
 	//		if token, ok := tg["token"].(string); ok && token != "" {
@@ -114,13 +203,22 @@ func buildSystemStatus() SystemStatus {
 
 
 	// Soul.md
-	soulPath := getSoulPath()
+	soulPath := getSoulPath(rt)
 	if _, err := os.Stat(soulPath); err == nil {
 		s.HasSoul = true
 	}
 
 	// Service status — OS-aware
-	s.ServiceStatus = getServiceStatus()
+	mgr := detectServiceManager()
+	s.ServiceStatus = mgr.Status()
+	s.ServiceManager = mgr.Name()
+	if cm, ok := mgr.(containerManager); ok {
+		s.ContainerStatus = cm.containerState()
+	}
+	s.Linger = lingerStatus(mgr)
+	drift := checkServiceDrift(mgr)
+	s.ServiceDrift = drift.Drifted
+	s.DriftReason = drift.Reason
 
 	// OS
 	if runtime.GOOS == "darwin" {
@@ -132,57 +230,47 @@ func buildSystemStatus() SystemStatus {
 	// Checklist
 	s.Checklist.System = s.PicoclawInstalled
 	s.Checklist.Provider = s.HasProvider
-	s.Checklist.Telegram = s.HasTelegram
+	s.Checklist.Channels = s.HealthyChannels > 0
 	s.Checklist.Soul = s.HasSoul
 	s.Checklist.Service = s.ServiceStatus == "active"
 
 	return s
 }
 
-// ------- Service Helpers -------
+// ------- RAM Helpers -------
 
-func getServiceStatus() string {
-	if runtime.GOOS == "darwin" {
-		// launchctl list returns a line with the label if loaded
-		out, err := runCommand("launchctl", "list", "com.picoclaw.agent")
-		if err == nil && !strings.Contains(out, "Could not find") && out != "" {
-			return "active"
-		}
-		return "inactive"
-	}
-	// Linux systemd
-	out, err := runCommand("systemctl", "--user", "is-active", "picoclaw")
-	if err == nil && strings.TrimSpace(out) == "active" {
-		return "active"
+func getRAM() string {
+	total, avail, err := memoryInfo()
+	if err != nil {
+		return "unavailable"
 	}
-	return "inactive"
+	return formatBytes(avail) + " free of " + formatBytes(total)
 }
 
-// ------- RAM Helpers -------
-
-func getRAM() string {
+// memoryInfo returns total and available RAM in bytes — OS-aware
+func memoryInfo() (int64, int64, error) {
 	if runtime.GOOS == "darwin" {
-		return getMacRAM()
+		return macMemory()
 	}
-	return getLinuxRAM()
+	return linuxMemory()
 }
 
 // macOS: vm_stat for free pages + sysctl for total
-func getMacRAM() string {
+func macMemory() (int64, int64, error) {
 	// Total RAM via sysctl
 	totalOut, err := runCommand("sysctl", "-n", "hw.memsize")
 	if err != nil {
-		return "unavailable"
+		return 0, 0, err
 	}
 	totalBytes, err := strconv.ParseInt(strings.TrimSpace(totalOut), 10, 64)
 	if err != nil {
-		return "unavailable"
+		return 0, 0, err
 	}
 
 	// Free pages via vm_stat
 	vmOut, err := runCommand("vm_stat")
 	if err != nil {
-		return "unavailable"
+		return 0, 0, err
 	}
 
 	var pageSize int64 = 4096
@@ -207,15 +295,14 @@ func getMacRAM() string {
 		}
 	}
 
-	freeBytes := (freePages + inactivePages) * pageSize
-	return formatBytes(freeBytes) + " free of " + formatBytes(totalBytes)
+	return totalBytes, (freePages + inactivePages) * pageSize, nil
 }
 
 // Linux: read /proc/meminfo directly — works everywhere, no column guessing
-func getLinuxRAM() string {
+func linuxMemory() (int64, int64, error) {
 	data, err := os.ReadFile("/proc/meminfo")
 	if err != nil {
-		return "unavailable"
+		return 0, 0, err
 	}
 
 	var totalKB, availKB int64
@@ -237,9 +324,9 @@ func getLinuxRAM() string {
 	}
 
 	if totalKB == 0 {
-		return "unavailable"
+		return 0, 0, fmt.Errorf("no MemTotal in /proc/meminfo")
 	}
-	return formatBytes(availKB*1024) + " free of " + formatBytes(totalKB*1024)
+	return totalKB * 1024, availKB * 1024, nil
 }
 
 func formatBytes(b int64) string {
@@ -260,34 +347,40 @@ type PicoConfig struct {
 	Tools	map[string]interface{}	`json:"tools,omitempty"`
 }
 
-func getConfigPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".picoclaw", "config.json")
+func getSoulPath(rt AgentRuntime) string {
+	return filepath.Join(rt.Workspace(), "SOUL.md")
 }
 
-func getSoulPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".picoclaw", "workspace", "SOUL.md")
+// readConfig and writeConfig go through the runtime, which maps its own
+// config file to and from PicoConfig. Handlers resolve the runtime once, so
+// a request doesn't re-read the wizard's settings for every config access.
+func readConfig(rt AgentRuntime) PicoConfig {
+	cfg, _ := rt.ReadConfig()
+	return cfg
 }
 
-func readConfig() PicoConfig {
-	var cfg PicoConfig
-	data, err := os.ReadFile(getConfigPath())
-	if err != nil {
-		return cfg
+func writeConfig(rt AgentRuntime, cfg PicoConfig) error {
+	return rt.WriteConfig(cfg)
+}
+
+// maskSecret keeps just enough of a token to recognise it in the UI
+func maskSecret(s string) string {
+	if len(s) <= 10 {
+		return strings.Repeat("*", len(s))
 	}
-	json.Unmarshal(data, &cfg)
-	return cfg
+	return s[:10] + "..."
 }
 
-func writeConfig(cfg PicoConfig) error {
-	path := getConfigPath()
-	os.MkdirAll(filepath.Dir(path), 0755)
-	data, err := json.MarshalIndent(cfg, "", " ")
-	if err != nil {
-		return err
+// stringList reads a JSON array of strings out of a decoded config map
+func stringList(v interface{}) []string {
+	var out []string
+	items, _ := v.([]interface{})
+	for _, item := range items {
+		if str, ok := item.(string); ok {
+			out = append(out, str)
+		}
 	}
-	return os.WriteFile(path, data, 0644)
+	return out
 }
 
 func jsonResponse(w http.ResponseWriter, v interface{}) {
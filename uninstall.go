@@ -0,0 +1,277 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	autorunMarker = "# claw-setup-autorun"
+	autologinConf = "/etc/systemd/system/getty@tty1.service.d/autologin.conf"
+)
+
+// UninstallOptions picks what to remove beyond the service itself
+type UninstallOptions struct {
+	RemoveBinary  bool
+	RemoveData    bool
+	RemoveAutorun bool
+}
+
+// UninstallResult lists what was done, in order, so the UI and CLI can echo it
+type UninstallResult struct {
+	Steps  []string `json:"steps"`
+	Backup string   `json:"backup,omitempty"`
+}
+
+// ── Uninstall ────────────────────────────────────────────────────────────────
+
+func handleUninstall(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+
+	res, err := uninstall(UninstallOptions{
+		RemoveBinary:  r.FormValue("remove_binary") == "true",
+		RemoveData:    r.FormValue("remove_data") == "true",
+		RemoveAutorun: r.FormValue("remove_autorun") == "true",
+	})
+	if err != nil {
+		jsonResponse(w, map[string]interface{}{
+			"ok":      false,
+			"message": err.Error(),
+			"steps":   res.Steps,
+			"backup":  res.Backup,
+		})
+		return
+	}
+	okResponse(w, currentRuntime().Name()+" uninstalled", map[string]interface{}{
+		"steps":  res.Steps,
+		"backup": res.Backup,
+	})
+}
+
+// runUninstallCLI handles `claw-setup uninstall [flags]`
+func runUninstallCLI(args []string) {
+	fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	binary := fs.Bool("binary", false, "also remove the agent binary (picoclaw or openclaw)")
+	data := fs.Bool("data", false, "also remove the agent's data directory, ~/.picoclaw or ~/.openclaw (a backup tarball is written first)")
+	autorun := fs.Bool("autorun", false, "also undo the tty1 auto-login and ~/.bashrc autorun set up by install.sh")
+	fs.Parse(args)
+
+	res, err := uninstall(UninstallOptions{RemoveBinary: *binary, RemoveData: *data, RemoveAutorun: *autorun})
+	for _, step := range res.Steps {
+		fmt.Println("✓", step)
+	}
+	if err != nil {
+		fmt.Println("❌", err)
+		os.Exit(1)
+	}
+	fmt.Println(currentRuntime().Name() + " uninstalled")
+}
+
+// uninstall removes the service first so nothing is running when the binary
+// and data go. Data is only deleted after its backup has been written.
+func uninstall(opts UninstallOptions) (UninstallResult, error) {
+	var res UninstallResult
+	restartGetty := false
+
+	mgr := detectServiceManager()
+	removeSchedules(mgr)
+	if err := mgr.Uninstall(); err != nil {
+		return res, fmt.Errorf("Failed to remove %s service: %v", mgr.Name(), err)
+	}
+	res.Steps = append(res.Steps, "Stopped and removed "+mgr.Name()+" service")
+
+	if opts.RemoveAutorun {
+		removed, err := removeMarkedBlock(".bashrc", autorunMarker)
+		if err != nil {
+			return res, fmt.Errorf("Failed to edit ~/.bashrc: %v", err)
+		}
+		if removed {
+			res.Steps = append(res.Steps, "Removed claw-setup autorun from ~/.bashrc")
+		}
+		// Only the drop-in install.sh wrote: raspi-config's console
+		// auto-login uses the same path
+		if data, err := os.ReadFile(autologinConf); err == nil && string(data) == installAutologinConf(currentUsername()) {
+			if err := removePrivilegedFile(autologinConf); err != nil {
+				return res, fmt.Errorf("Failed to remove tty1 auto-login: %v", err)
+			}
+			os.Remove(filepath.Dir(autologinConf)) // only succeeds when empty
+			if err := runPrivileged("systemctl", "daemon-reload"); err != nil {
+				return res, fmt.Errorf("Removed tty1 auto-login, but reloading systemd failed: %v", err)
+			}
+			restartGetty = true
+			res.Steps = append(res.Steps, "Disabled tty1 auto-login")
+		}
+	}
+
+	rt := currentRuntime()
+	if opts.RemoveBinary {
+		if path, err := exec.LookPath(rt.Binary()); err == nil {
+			if err := rt.Uninstall(path); err != nil {
+				return res, fmt.Errorf("Failed to remove %s: %v", path, err)
+			}
+			res.Steps = append(res.Steps, "Removed "+path)
+			if filepath.Dir(path) == userBinDir() {
+				if removed, _ := removeMarkedBlock(".profile", userPathMarker); removed {
+					res.Steps = append(res.Steps, "Removed ~/.local/bin PATH entry from ~/.profile")
+				}
+			}
+		}
+	}
+
+	if opts.RemoveData {
+		home, _ := os.UserHomeDir()
+		dir := rt.DataDir()
+		short := "~/" + filepath.Base(dir)
+		if _, err := os.Stat(dir); err == nil {
+			backup := filepath.Join(home, fmt.Sprintf("%s-backup-%s.tar.gz", rt.ID(), time.Now().Format("20060102-150405")))
+			if err := tarDirectory(dir, backup); err != nil {
+				os.Remove(backup)
+				return res, fmt.Errorf("Backup failed, %s was kept: %v", short, err)
+			}
+			res.Backup = backup
+			res.Steps = append(res.Steps, "Backed up "+short+" to "+backup)
+			if err := os.RemoveAll(dir); err != nil {
+				return res, fmt.Errorf("Failed to remove %s: %v", short, err)
+			}
+			res.Steps = append(res.Steps, "Removed "+short)
+		}
+	}
+
+	// Last, since this may itself be running in the tty1 session: getty
+	// keeps the auto-login it started with until it restarts
+	if restartGetty {
+		if err := runPrivileged("systemctl", "restart", "--no-block", "getty@tty1.service"); err != nil {
+			return res, fmt.Errorf("Removed tty1 auto-login, but restarting getty@tty1 failed: %v", err)
+		}
+	}
+	return res, nil
+}
+
+// ── Uninstall Helpers ────────────────────────────────────────────────────────
+
+// installAutologinConf is the getty drop-in install.sh writes for user
+func installAutologinConf(user string) string {
+	return "[Service]\nExecStart=\nExecStart=-/sbin/agetty --autologin " + user + " --noclear %I $TERM\n"
+}
+
+// removeMarkedBlock strips a block claw-setup appended to a dotfile in
+// ~: the marker line, the blank line before it, and what follows it — an
+// `if … fi` (install.sh's tty1 autorun) or else the single line under it
+// (addUserBinToPath's PATH entry).
+func removeMarkedBlock(name, marker string) (bool, error) {
+	home, _ := os.UserHomeDir()
+	path := filepath.Join(home, name)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	var out []string
+	removed := false
+	for i := 0; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != marker {
+			out = append(out, lines[i])
+			continue
+		}
+		removed = true
+		if n := len(out); n > 0 && strings.TrimSpace(out[n-1]) == "" {
+			out = out[:n-1]
+		}
+		if i+1 < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i+1]), "if ") {
+			i++
+			continue
+		}
+		for i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), "if ") {
+			i++
+			for i+1 < len(lines) {
+				i++
+				if strings.TrimSpace(lines[i]) == "fi" {
+					break
+				}
+			}
+		}
+	}
+	if !removed {
+		return false, nil
+	}
+
+	info, _ := os.Stat(path)
+	return true, os.WriteFile(path, []byte(strings.Join(out, "\n")), info.Mode().Perm())
+}
+
+func removeBinary(path string) error {
+	if err := os.Remove(path); err == nil || os.IsNotExist(err) {
+		return nil
+	}
+	return runPrivileged("rm", "-f", path)
+}
+
+// tarDirectory writes dir to a gzipped tarball rooted at the directory's name
+func tarDirectory(dir, dest string) error {
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	base := filepath.Dir(dir)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(base, path)
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
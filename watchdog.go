@@ -0,0 +1,439 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	watchdogUnit       = serviceName + "-watchdog"
+	nightlyUnit        = serviceName + "-nightly"
+	watchdogLabel      = "com.picoclaw.watchdog"
+	nightlyLabel       = "com.picoclaw.nightly"
+	defaultGatewayPort = 18790
+	// A system-level watchdog runs as root, so it runs a root-owned copy of
+	// the wizard rather than the user-writable binary self-update replaces,
+	// and keeps its settings and state out of the user's home
+	watchdogBinary     = "/usr/local/libexec/claw-setup"
+	watchdogStateDir   = serviceName + "-watchdog"
+	systemStatePath    = "/var/lib/" + watchdogStateDir + "/watchdog.json"
+	systemSettingsPath = "/var/lib/" + watchdogStateDir + "/settings.json"
+)
+
+var restartAtPattern = regexp.MustCompile(`^([01]\d|2[0-3]):([0-5]\d)$`)
+
+// WatchdogSettings configures the periodic health probe and the optional
+// nightly restart. Probe is "process", "port" or "log".
+type WatchdogSettings struct {
+	Enabled         bool   `json:"enabled"`
+	Probe           string `json:"probe"`
+	Port            int    `json:"port"`
+	IntervalMinutes int    `json:"interval_minutes"`
+	StaleMinutes    int    `json:"stale_minutes"`
+	NightlyRestart  bool   `json:"nightly_restart"`
+	RestartAt       string `json:"restart_at"`
+}
+
+// WatchdogState is what the last probe saw, kept between runs so the log
+// probe can tell how long output has been unchanged.
+type WatchdogState struct {
+	LastCheck   time.Time `json:"last_check"`
+	LastResult  string    `json:"last_result"`
+	LastRestart time.Time `json:"last_restart"`
+	LogHash     string    `json:"log_hash"`
+	LogSince    time.Time `json:"log_since"`
+}
+
+func defaultWatchdog() WatchdogSettings {
+	return WatchdogSettings{
+		Probe:           "process",
+		Port:            defaultGatewayPort,
+		IntervalMinutes: 5,
+		StaleMinutes:    30,
+		RestartAt:       "04:00",
+	}
+}
+
+func watchdogSettings() WatchdogSettings {
+	if w := readSettings().Watchdog; w != nil {
+		return *w
+	}
+	return defaultWatchdog()
+}
+
+func watchdogStatePath(mgr ServiceManager) string {
+	if m, ok := mgr.(systemdManager); ok && !m.user {
+		return systemStatePath
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".picoclaw", "watchdog.json")
+}
+
+// ── Watchdog ─────────────────────────────────────────────────────────────────
+
+// handleWatchdog returns settings and the last probe on GET; POST saves the
+// settings and installs or removes the timers to match.
+func handleWatchdog(w http.ResponseWriter, r *http.Request) {
+	mgr := detectServiceManager()
+	if r.Method != http.MethodPost {
+		jsonResponse(w, map[string]interface{}{
+			"watchdog":  watchdogSettings(),
+			"state":     readWatchdogState(mgr),
+			"supported": schedulesSupported(mgr),
+		})
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+
+	ws := WatchdogSettings{
+		Enabled:        r.FormValue("enabled") == "true",
+		Probe:          r.FormValue("probe"),
+		NightlyRestart: r.FormValue("nightly_restart") == "true",
+		RestartAt:      strings.TrimSpace(r.FormValue("restart_at")),
+	}
+	ws.Port, _ = strconv.Atoi(r.FormValue("port"))
+	ws.IntervalMinutes, _ = strconv.Atoi(r.FormValue("interval_minutes"))
+	ws.StaleMinutes, _ = strconv.Atoi(r.FormValue("stale_minutes"))
+	if err := ws.validate(); err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+	if (ws.Enabled || ws.NightlyRestart) && !schedulesSupported(mgr) {
+		errorResponse(w, "Scheduled checks need systemd or launchd — "+mgr.Name()+" isn't supported")
+		return
+	}
+
+	settings := readSettings()
+	settings.Watchdog = &ws
+	if err := writeSettings(settings); err != nil {
+		errorResponse(w, "Failed to save settings: "+err.Error())
+		return
+	}
+	if err := applySchedules(mgr, ws); err != nil {
+		errorResponse(w, "Saved, but installing the timers failed: "+err.Error())
+		return
+	}
+	okResponse(w, "Watchdog settings applied", nil)
+}
+
+// runWatchdogCLI handles `claw-setup watchdog`, which the timer runs: one
+// probe, and a restart if the agent looks stuck.
+func runWatchdogCLI(args []string) {
+	fs := flag.NewFlagSet("watchdog", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "probe and report, but don't restart")
+	system := fs.Bool("system", false, "watch the system service, with the settings in "+systemSettingsPath)
+	fs.Parse(args)
+
+	ws, mgr := watchdogSettings(), detectServiceManager()
+	if *system {
+		// This runs as root: claw-setup.json is the user's to edit, so
+		// only the root-owned copy applySchedules made is trusted
+		ws, mgr = systemWatchdogSettings(), systemdManager{}
+	}
+	state := readWatchdogState(mgr)
+	state.LastCheck = time.Now()
+
+	healthy, detail := probeAgent(mgr, ws, &state)
+	state.LastResult = detail
+	fmt.Println(detail)
+	if !healthy && !*dryRun {
+		if err := mgr.Restart(); err != nil {
+			state.LastResult += " — restart failed: " + err.Error()
+			fmt.Println("restart failed:", err)
+		} else {
+			state.LastRestart = time.Now()
+			state.LastResult += " — restarted"
+			fmt.Println("restarted", serviceName)
+		}
+	}
+	writeWatchdogState(mgr, state)
+}
+
+// probeAgent only judges a running service — a stopped one was either
+// stopped on purpose or is already Restart=on-failure's job.
+func probeAgent(mgr ServiceManager, ws WatchdogSettings, state *WatchdogState) (bool, string) {
+	if mgr.Status() != "active" {
+		return true, "Service not active — nothing to check"
+	}
+
+	switch ws.Probe {
+	case "port":
+		conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", ws.Port), 5*time.Second)
+		if err != nil {
+			return false, fmt.Sprintf("Gateway port %d not answering", ws.Port)
+		}
+		conn.Close()
+		return true, fmt.Sprintf("Gateway port %d answering", ws.Port)
+
+	case "log":
+		out, err := mgr.Logs(20)
+		if err != nil {
+			return true, "Could not read logs: " + err.Error()
+		}
+		sum := sha256.Sum256([]byte(out))
+		hash := hex.EncodeToString(sum[:])
+		if hash != state.LogHash {
+			state.LogHash, state.LogSince = hash, time.Now()
+			return true, "Log output is moving"
+		}
+		quiet := time.Since(state.LogSince)
+		if quiet > time.Duration(ws.StaleMinutes)*time.Minute {
+			state.LogSince = time.Now()
+			return false, fmt.Sprintf("No new log output for %d minutes", int(quiet.Minutes()))
+		}
+		return true, fmt.Sprintf("Log quiet for %d minutes", int(quiet.Minutes()))
+	}
+
+	// Match the binary the installed service runs — the timer's PATH may
+	// not be able to find picoclaw itself
+	path, _ := mgr.Definition(ServiceSpec{})
+	content, _ := os.ReadFile(path)
+	exe := definedExecPath(string(content))
+	if exe == "" {
+		return true, "Could not tell which binary the service runs"
+	}
+	out, err := runCommand("pgrep", "-f", regexp.QuoteMeta(exe))
+	if err != nil {
+		return false, "Service active but no agent process found"
+	}
+	for _, pid := range strings.Fields(out) {
+		// A stopped (T) or zombie (Z) process holds the unit up without doing anything
+		if stat, err := os.ReadFile("/proc/" + pid + "/stat"); err == nil {
+			if f := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:])); len(f) > 0 && (f[0] == "T" || f[0] == "Z") {
+				return false, "Agent process " + pid + " is stopped or defunct"
+			}
+		}
+	}
+	return true, "Agent process running"
+}
+
+func (ws WatchdogSettings) validate() error {
+	switch ws.Probe {
+	case "process", "port", "log":
+	default:
+		return fmt.Errorf("Unknown probe %q", ws.Probe)
+	}
+	if ws.Probe == "port" && (ws.Port <= 0 || ws.Port > 65535) {
+		return fmt.Errorf("Port must be between 1 and 65535")
+	}
+	if ws.IntervalMinutes < 1 {
+		return fmt.Errorf("Check interval must be at least 1 minute")
+	}
+	if ws.Probe == "log" && ws.StaleMinutes < ws.IntervalMinutes {
+		return fmt.Errorf("Quiet period must be at least the check interval")
+	}
+	if ws.NightlyRestart && !restartAtPattern.MatchString(ws.RestartAt) {
+		return fmt.Errorf("Restart time must be HH:MM")
+	}
+	return nil
+}
+
+// ── Schedules ────────────────────────────────────────────────────────────────
+
+func schedulesSupported(mgr ServiceManager) bool {
+	switch mgr.(type) {
+	case systemdManager, launchdManager:
+		return true
+	}
+	return false
+}
+
+// applySchedules installs the watchdog and nightly timers that are switched
+// on and removes those that aren't.
+func applySchedules(mgr ServiceManager, ws WatchdogSettings) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	home, _ := os.UserHomeDir()
+
+	switch m := mgr.(type) {
+	case systemdManager:
+		restart := []string{"systemctl", "restart", serviceName + ".service"}
+		if m.user {
+			restart = []string{"systemctl", "--user", "restart", serviceName + ".service"}
+		}
+		if path, err := exec.LookPath("systemctl"); err == nil {
+			restart[0] = path
+		}
+		command := shellQuote(self) + " watchdog"
+		env := "Environment=HOME=" + home + "\n"
+		if !m.user {
+			if ws.Enabled {
+				if err := runPrivileged("install", "-D", "-m", "0755", self, watchdogBinary); err != nil {
+					return fmt.Errorf("Could not install %s: %v", watchdogBinary, err)
+				}
+				data, err := json.MarshalIndent(ws, "", " ")
+				if err != nil {
+					return err
+				}
+				if err := writePrivilegedFile(systemSettingsPath, string(data), 0644); err != nil {
+					return fmt.Errorf("Could not write %s: %v", systemSettingsPath, err)
+				}
+			} else {
+				if err := removePrivilegedFile(watchdogBinary); err != nil {
+					return err
+				}
+				if err := removePrivilegedFile(systemSettingsPath); err != nil {
+					return err
+				}
+			}
+			command = shellQuote(watchdogBinary) + " watchdog -system"
+			env = "StateDirectory=" + watchdogStateDir + "\n"
+		}
+		if err := m.setTimer(watchdogUnit, ws.Enabled, "PicoClaw watchdog", command, env,
+			fmt.Sprintf("OnBootSec=5min\nOnUnitActiveSec=%dmin", ws.IntervalMinutes)); err != nil {
+			return err
+		}
+		return m.setTimer(nightlyUnit, ws.NightlyRestart, "PicoClaw nightly restart",
+			strings.Join(restart, " "), "Environment=HOME="+home+"\n",
+			fmt.Sprintf("OnCalendar=*-*-* %s:00", ws.RestartAt))
+
+	case launchdManager:
+		if err := setLaunchdJob(watchdogLabel, ws.Enabled, []string{self, "watchdog"},
+			fmt.Sprintf("\t<key>StartInterval</key>\n\t<integer>%d</integer>\n", ws.IntervalMinutes*60)); err != nil {
+			return err
+		}
+		hour, minute := 4, 0
+		if m := restartAtPattern.FindStringSubmatch(ws.RestartAt); m != nil {
+			hour, _ = strconv.Atoi(m[1])
+			minute, _ = strconv.Atoi(m[2])
+		}
+		return setLaunchdJob(nightlyLabel, ws.NightlyRestart,
+			[]string{"/bin/launchctl", "kickstart", "-k", launchdTarget()},
+			fmt.Sprintf("\t<key>StartCalendarInterval</key>\n\t<dict>\n\t\t<key>Hour</key>\n\t\t<integer>%d</integer>\n\t\t<key>Minute</key>\n\t\t<integer>%d</integer>\n\t</dict>\n", hour, minute))
+	}
+	return nil
+}
+
+// removeSchedules tears down both timers, e.g. on uninstall
+func removeSchedules(mgr ServiceManager) {
+	applySchedules(mgr, WatchdogSettings{})
+}
+
+// setTimer writes (or removes) a oneshot service plus the timer that fires
+// it. A system-level watchdog runs as root so it can restart the agent, and
+// reads nothing from the user's home.
+func (m systemdManager) setTimer(name string, enabled bool, desc, command, env, schedule string) error {
+	if !enabled {
+		m.systemctl("disable", "--now", name+".timer")
+		m.removeUnitFile(name + ".timer")
+		return m.removeUnitFile(name + ".service")
+	}
+
+	service := fmt.Sprintf(`[Unit]
+Description=%s
+
+[Service]
+Type=oneshot
+ExecStart=%s
+%s`, desc, command, env)
+	timer := fmt.Sprintf(`[Unit]
+Description=%s timer
+
+[Timer]
+%s
+AccuracySec=1min
+
+[Install]
+WantedBy=timers.target
+`, desc, schedule)
+
+	if err := m.writeUnitFile(name+".service", service); err != nil {
+		return err
+	}
+	if err := m.writeUnitFile(name+".timer", timer); err != nil {
+		return err
+	}
+	if err := m.systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	// restart rather than start so a changed schedule takes effect
+	if err := m.systemctl("enable", name+".timer"); err != nil {
+		return err
+	}
+	return m.systemctl("restart", name+".timer")
+}
+
+func setLaunchdJob(label string, enabled bool, args []string, schedule string) error {
+	home, _ := os.UserHomeDir()
+	path := filepath.Join(home, "Library", "LaunchAgents", label+".plist")
+	target := launchdDomain() + "/" + label
+	exec.Command("launchctl", "bootout", target).Run()
+	if !enabled {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	var argsXML strings.Builder
+	for _, a := range args {
+		fmt.Fprintf(&argsXML, "\t\t<string>%s</string>\n", xmlEscape(a))
+	}
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>HOME</key>
+		<string>%s</string>
+	</dict>
+%s</dict>
+</plist>
+`, xmlEscape(label), argsXML.String(), xmlEscape(home), schedule)
+
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(plist), 0644); err != nil {
+		return err
+	}
+	return run("launchctl", "bootstrap", launchdDomain(), path)
+}
+
+// ── Watchdog Helpers ─────────────────────────────────────────────────────────
+
+// systemWatchdogSettings reads the root-owned copy of the watchdog settings
+// a system-level watchdog runs with
+func systemWatchdogSettings() WatchdogSettings {
+	ws := defaultWatchdog()
+	if data, err := os.ReadFile(systemSettingsPath); err == nil {
+		json.Unmarshal(data, &ws)
+	}
+	return ws
+}
+
+func readWatchdogState(mgr ServiceManager) WatchdogState {
+	var s WatchdogState
+	data, err := os.ReadFile(watchdogStatePath(mgr))
+	if err != nil {
+		return s
+	}
+	json.Unmarshal(data, &s)
+	return s
+}
+
+func writeWatchdogState(mgr ServiceManager, s WatchdogState) error {
+	data, err := json.MarshalIndent(s, "", " ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(watchdogStatePath(mgr), data, 0644)
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWhatsAppBridge  = "ws://localhost:3001"
+	defaultWhatsAppPairCmd = "picoclaw whatsapp login"
+)
+
+// WhatsApp multi-device QR payloads look like "2@AbC...,xyz=,abc=,def="
+var whatsappQRPattern = regexp.MustCompile(`\b\d@[A-Za-z0-9+/=]+(,[A-Za-z0-9+/=]+){2,}\b`)
+
+// Log lines bridges print once the phone has been linked. Anchored after
+// any timestamp or [tag] prefix, so "not connected" and "disconnected"
+// don't count; a bare "connected" is the bridge itself, not the phone.
+var whatsappLinkedPattern = regexp.MustCompile(`(?i)^(?:\[[^\]]*\]\s*|[\d:./TZ+-]+\s*)*(?:(?:whatsapp\s+)?(?:logged in|login successful|paired)|whatsapp\s+connected)\b`)
+
+// WhatsAppPairState is what the browser sees of a pairing session
+type WhatsAppPairState struct {
+	State   string   `json:"state"` // idle, starting, qr, linked, failed
+	QR      string   `json:"qr"`
+	Message string   `json:"message"`
+	Log     []string `json:"log"`
+}
+
+// WhatsAppPairing tracks the one pairing process the wizard may run at a time
+type WhatsAppPairing struct {
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	state   WhatsAppPairState
+	version int
+}
+
+var whatsappPairing = &WhatsAppPairing{state: WhatsAppPairState{State: "idle"}}
+
+func (p *WhatsAppPairing) update(fn func(s *WhatsAppPairState)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn(&p.state)
+	p.version++
+}
+
+func (p *WhatsAppPairing) snapshot() (WhatsAppPairState, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	snap := p.state
+	snap.Log = append([]string(nil), p.state.Log...)
+	return snap, p.version
+}
+
+// ── WhatsApp ─────────────────────────────────────────────────────────────────
+
+func handleWhatsAppPairStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+	// Always the runtime's own login command: the wizard has no login, so
+	// a command from the request would let anyone on the LAN run anything
+	rt := currentRuntime()
+	command := rt.WhatsAppLoginCommand()
+	bridge := strings.TrimSpace(r.FormValue("bridge_url"))
+	if bridge == "" {
+		bridge = defaultWhatsAppBridge
+	}
+	if _, err := url.Parse(bridge); err != nil {
+		errorResponse(w, "Invalid bridge URL: "+err.Error())
+		return
+	}
+
+	cfg := readConfig(rt)
+	if cfg.Channels == nil {
+		cfg.Channels = make(map[string]map[string]interface{})
+	}
+	wa := cfg.Channels["whatsapp"]
+	if wa == nil {
+		wa = make(map[string]interface{})
+	}
+	wa["bridge_url"] = bridge
+	cfg.Channels["whatsapp"] = wa
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
+
+	if err := whatsappPairing.start(strings.Fields(command)); err != nil {
+		errorResponse(w, err.Error())
+		return
+	}
+
+	okResponse(w, "Pairing started", nil)
+}
+
+func handleWhatsAppPairStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	whatsappPairing.stop()
+	okResponse(w, "Pairing stopped", nil)
+}
+
+// handleWhatsAppPairEvents streams pairing state changes (QR refreshes, link
+// status, log lines) to the browser as server-sent events.
+func handleWhatsAppPairEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	last := -1
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		snap, version := whatsappPairing.snapshot()
+		if version != last {
+			last = version
+			b, _ := json.Marshal(snap)
+			fmt.Fprintf(w, "data: %s\n\n", b)
+			flusher.Flush()
+		}
+		if snap.State == "linked" || snap.State == "failed" || snap.State == "idle" {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func handleWhatsAppStatus(w http.ResponseWriter, r *http.Request) {
+	cfg := readConfig(currentRuntime())
+	bridge, _ := cfg.Channels["whatsapp"]["bridge_url"].(string)
+	if bridge == "" {
+		bridge = defaultWhatsAppBridge
+	}
+	snap, _ := whatsappPairing.snapshot()
+	jsonResponse(w, map[string]interface{}{
+		"ok":               true,
+		"state":            snap.State,
+		"bridge_url":       bridge,
+		"bridge_reachable": bridgeReachable(bridge),
+	})
+}
+
+func handleSaveWhatsAppNumbers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseMultipartForm(10 << 20)
+
+	var numbers []string
+	for _, n := range splitLines(r.FormValue("numbers")) {
+		norm, ok := normalizePhone(n)
+		if !ok {
+			errorResponse(w, "Not a phone number: "+n)
+			return
+		}
+		numbers = append(numbers, norm)
+	}
+	if len(numbers) == 0 {
+		errorResponse(w, "numbers is required")
+		return
+	}
+
+	rt := currentRuntime()
+	cfg := readConfig(rt)
+	if cfg.Channels == nil {
+		cfg.Channels = make(map[string]map[string]interface{})
+	}
+	wa := cfg.Channels["whatsapp"]
+	if wa == nil {
+		wa = make(map[string]interface{})
+	}
+	if _, ok := wa["bridge_url"]; !ok {
+		wa["bridge_url"] = defaultWhatsAppBridge
+	}
+	wa["enabled"] = true
+	wa["allowFrom"] = numbers
+	cfg.Channels["whatsapp"] = wa
+	if err := writeConfig(rt, cfg); err != nil {
+		errorResponse(w, "Failed to save config: "+err.Error())
+		return
+	}
+	okResponse(w, fmt.Sprintf("%d number(s) saved", len(numbers)), map[string]interface{}{
+		"numbers": numbers,
+	})
+}
+
+// ── WhatsApp Helpers ─────────────────────────────────────────────────────────
+
+func (p *WhatsAppPairing) start(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("pairing command is empty")
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return fmt.Errorf("%s not found in PATH", args[0])
+	}
+
+	p.mu.Lock()
+	if p.cmd != nil {
+		p.mu.Unlock()
+		return fmt.Errorf("a pairing session is already running")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		p.mu.Unlock()
+		return fmt.Errorf("could not start %s: %v", args[0], err)
+	}
+	p.cmd = cmd
+	p.state = WhatsAppPairState{State: "starting", Message: "Waiting for QR code..."}
+	p.version++
+	p.mu.Unlock()
+
+	go func() {
+		scanner := bufio.NewScanner(pipe)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			p.handleLine(scanner.Text())
+		}
+		err := cmd.Wait()
+		p.update(func(s *WhatsAppPairState) {
+			p.cmd = nil
+			if s.State == "linked" || s.State == "idle" {
+				return
+			}
+			s.State = "failed"
+			if err != nil {
+				s.Message = "Pairing exited: " + err.Error()
+			} else {
+				s.Message = "Pairing exited before the phone was linked"
+			}
+		})
+	}()
+	return nil
+}
+
+func (p *WhatsAppPairing) handleLine(line string) {
+	p.update(func(s *WhatsAppPairState) {
+		if qr := whatsappQRPattern.FindString(line); qr != "" {
+			s.State = "qr"
+			s.QR = qr
+			s.Message = "Scan the QR code with WhatsApp → Linked devices"
+			return
+		}
+		// Terminal QR art is useless in the browser — keep it out of the log
+		if strings.ContainsAny(line, "█▀▄") {
+			return
+		}
+		s.Log = append(s.Log, line)
+		if len(s.Log) > 50 {
+			s.Log = s.Log[len(s.Log)-50:]
+		}
+		if whatsappLinkedPattern.MatchString(line) {
+			s.State = "linked"
+			s.QR = ""
+			s.Message = "Phone linked"
+		}
+	})
+}
+
+func (p *WhatsAppPairing) stop() {
+	p.mu.Lock()
+	cmd := p.cmd
+	p.mu.Unlock()
+	if cmd != nil && cmd.Process != nil {
+		cmd.Process.Kill()
+	}
+	p.update(func(s *WhatsAppPairState) {
+		if s.State != "linked" {
+			*s = WhatsAppPairState{State: "idle"}
+		}
+	})
+}
+
+// bridgeReachable checks whether anything is listening on the bridge address
+func bridgeReachable(bridge string) bool {
+	u, err := url.Parse(bridge)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := u.Host
+	if u.Port() == "" {
+		if u.Scheme == "wss" || u.Scheme == "https" {
+			host += ":443"
+		} else {
+			host += ":80"
+		}
+	}
+	conn, err := net.DialTimeout("tcp", host, 2*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// normalizePhone strips formatting and returns the number in international
+// form without the leading +, as WhatsApp JIDs use it.
+func normalizePhone(s string) (string, bool) {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+		default:
+			return "", false
+		}
+	}
+	n := b.String()
+	if len(n) < 7 || len(n) > 15 {
+		return "", false
+	}
+	return n, true
+}